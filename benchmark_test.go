@@ -2,6 +2,8 @@ package timeseries
 
 import (
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -62,6 +64,46 @@ func BenchmarkA_Store(b *testing.B) {
 	}
 }
 
+// BenchmarkA1_StoreBatch - stores 100,000 items in batches of 1,000 into a separate directory
+func BenchmarkA1_StoreBatch(b *testing.B) {
+	batchSize := 1000
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		dir, err := os.MkdirTemp("", "bench-timeseries-batch-*")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		c, err := Init[benchStruct](Options{Path: dir})
+		if err != nil {
+			os.RemoveAll(dir)
+			b.Fatal(err)
+		}
+
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		batch := make([]Entry[benchStruct], 0, batchSize)
+		b.StartTimer()
+
+		for j := 0; j < benchmarkItemCount; j++ {
+			batch = append(batch, Entry[benchStruct]{
+				Time: base.Add(time.Duration(j) * time.Minute),
+				Data: benchStruct{ID: j, Name: "benchmark", Value: float64(j)},
+			})
+			if len(batch) == batchSize {
+				if err := c.StoreBatch(batch); err != nil {
+					b.Fatal(err)
+				}
+				batch = batch[:0]
+			}
+		}
+
+		b.StopTimer()
+		os.RemoveAll(dir)
+		b.StartTimer()
+	}
+}
+
 // BenchmarkB_Get runs second - retrieves all 100,000 items
 func BenchmarkB_Get(b *testing.B) {
 	if benchClient == nil {
@@ -80,6 +122,63 @@ func BenchmarkB_Get(b *testing.B) {
 	}
 }
 
+// BenchmarkB1_GetCompressed - stores 100,000 items with each codec in batches of one hour and retrieves them all, reporting disk usage
+func BenchmarkB1_GetCompressed(b *testing.B) {
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd, CompressionSnappy} {
+		b.Run(compression, func(b *testing.B) {
+			dir, err := os.MkdirTemp("", "bench-timeseries-compressed-*")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			c, err := Init[benchStruct](Options{Path: dir, Compression: compression})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			end := base.Add(time.Duration(benchmarkItemCount-1) * time.Minute)
+			batch := make([]Entry[benchStruct], 0, 60)
+			for j := 0; j < benchmarkItemCount; j++ {
+				batch = append(batch, Entry[benchStruct]{
+					Time: base.Add(time.Duration(j) * time.Minute),
+					Data: benchStruct{ID: j, Name: "benchmark", Value: float64(j)},
+				})
+				if len(batch) == cap(batch) || j == benchmarkItemCount-1 {
+					if err := c.StoreBatch(batch); err != nil {
+						b.Fatal(err)
+					}
+					batch = batch[:0]
+				}
+			}
+
+			var diskBytes int64
+			err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					diskBytes += info.Size()
+				}
+				return err
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				results, err := c.Get(base, end)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(results) != benchmarkItemCount {
+					b.Fatalf("expected %d results, got %d", benchmarkItemCount, len(results))
+				}
+			}
+			b.ReportMetric(float64(diskBytes), "disk-bytes")
+		})
+	}
+}
+
 // BenchmarkC1_Find_1Minute - searches within 1 minute window (1 item)
 func BenchmarkC1_Find_1Minute(b *testing.B) {
 	if benchClient == nil {
@@ -224,6 +323,39 @@ func BenchmarkC5_Find_1Month(b *testing.B) {
 	}
 }
 
+// BenchmarkC5P_Find_1Month_Parallel - same window as BenchmarkC5_Find_1Month, read with one worker per CPU
+func BenchmarkC5P_Find_1Month_Parallel(b *testing.B) {
+	if benchClient == nil {
+		b.Skip("BenchmarkA_Store must run first")
+	}
+
+	parallel, err := Init[benchStruct](Options{Path: benchTmpDir, ReadWorkers: runtime.NumCPU()})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	itemCount := 30 * 24 * 60
+	windowEnd := benchBase.Add(time.Duration(itemCount-1) * time.Minute)
+	targetID := itemCount - 1
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var found *benchStruct
+		err := parallel.Find(benchBase, windowEnd, func(t time.Time, data benchStruct) bool {
+			if data.ID == targetID {
+				found = &data
+			}
+			return true
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if found == nil || found.ID != targetID {
+			b.Fatalf("expected to find item with ID %d", targetID)
+		}
+	}
+}
+
 // BenchmarkC6_Find_Full - searches entire dataset for item at end (100000 items)
 func BenchmarkC6_Find_Full(b *testing.B) {
 	if benchClient == nil {
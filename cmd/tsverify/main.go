@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/zveinn/timeseries"
+)
+
+func main() {
+	path := flag.String("path", "", "store directory to verify")
+	indent := flag.Bool("indent", false, "indent the JSON report")
+	flag.Parse()
+
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "usage: tsverify -path <store directory>")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := timeseries.Init[cbor.RawMessage](timeseries.Options{Path: *path, ReadOnly: true})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	report, err := client.Verify(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	if *indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(report.Issues) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,199 @@
+package timeseries
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionZstd   = "zstd"
+	CompressionSnappy = "snappy"
+
+	blockMarker     = 0xfc
+	blockHeaderSize = 9
+)
+
+var (
+	ErrUnknownCompression = errors.New("unknown compression")
+	ErrInvalidBlock       = errors.New("invalid compressed block")
+)
+
+type codec struct {
+	ext        string
+	compress   func(dst *bytes.Buffer, src []byte) error
+	decompress func(src []byte) ([]byte, error)
+}
+
+var codecs = map[string]*codec{
+	CompressionNone: {
+		ext: ".cbor",
+	},
+	CompressionGzip: {
+		ext: ".cbor.gz",
+		compress: func(dst *bytes.Buffer, src []byte) error {
+			w := gzip.NewWriter(dst)
+			if _, err := w.Write(src); err != nil {
+				return err
+			}
+			return w.Close()
+		},
+		decompress: func(src []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(src))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(r)
+		},
+	},
+	CompressionZstd: {
+		ext: ".cbor.zst",
+		compress: func(dst *bytes.Buffer, src []byte) error {
+			enc, _ := zstdCoders()
+			dst.Write(enc.EncodeAll(src, nil))
+			return nil
+		},
+		decompress: func(src []byte) ([]byte, error) {
+			_, dec := zstdCoders()
+			return dec.DecodeAll(src, nil)
+		},
+	},
+	CompressionSnappy: {
+		ext: ".cbor.sz",
+		compress: func(dst *bytes.Buffer, src []byte) error {
+			dst.Write(snappy.Encode(nil, src))
+			return nil
+		},
+		decompress: func(src []byte) ([]byte, error) {
+			return snappy.Decode(nil, src)
+		},
+	},
+}
+
+var zstdCoders = sync.OnceValues(func() (*zstd.Encoder, *zstd.Decoder) {
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		panic(err)
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		panic(err)
+	}
+	return enc, dec
+})
+
+func (c *Client[T]) compression() string {
+	if c.Opts.Compression == "" {
+		return CompressionNone
+	}
+	return c.Opts.Compression
+}
+
+func (c *Client[T]) codec() *codec {
+	return codecs[c.compression()]
+}
+
+func (c *Client[T]) ext() string {
+	return c.codec().ext
+}
+
+func (c *Client[T]) compressBlock(dst *bytes.Buffer, plain []byte) error {
+	codec := c.codec()
+	if codec.compress == nil {
+		dst.Write(plain)
+		return nil
+	}
+
+	var block bytes.Buffer
+	if err := codec.compress(&block, plain); err != nil {
+		return err
+	}
+
+	var header [blockHeaderSize]byte
+	header[0] = blockMarker
+	binary.BigEndian.PutUint32(header[1:5], uint32(block.Len()))
+	binary.BigEndian.PutUint32(header[5:9], lengthSum(header[1:5], block.Bytes()))
+	dst.Write(header[:])
+	dst.Write(block.Bytes())
+	return nil
+}
+
+func (codec *codec) block(buf []byte, off int) ([]byte, int, error) {
+	if buf[off] != blockMarker {
+		return nil, 0, ErrInvalidBlock
+	}
+
+	if len(buf)-off < blockHeaderSize {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	size := int(binary.BigEndian.Uint32(buf[off+1 : off+5]))
+	end := off + blockHeaderSize + size
+	if end > len(buf) || end < off {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	data := buf[off+blockHeaderSize : end]
+	if lengthSum(buf[off+1:off+5], data) != binary.BigEndian.Uint32(buf[off+5:off+9]) {
+		return nil, end, ErrChecksumMismatch
+	}
+
+	plain, err := codec.decompress(data)
+	return plain, end, err
+}
+
+func (codec *codec) nextBlock(buf []byte, from int) int {
+	for off := from; off < len(buf); off++ {
+		if buf[off] != blockMarker {
+			continue
+		}
+		_, end, err := codec.block(buf, off)
+		if err == nil && (end == len(buf) || buf[end] == blockMarker) {
+			return off
+		}
+	}
+	return -1
+}
+
+func (c *Client[T]) decompress(buf []byte, onCorrupt func(offset int64, err error)) ([]byte, int64, error) {
+	codec := c.codec()
+	if codec.decompress == nil {
+		return buf, int64(len(buf)), nil
+	}
+
+	var plain []byte
+	off := 0
+	for off < len(buf) {
+		data, end, err := codec.block(buf, off)
+		if err != nil {
+			next := codec.nextBlock(buf, off+1)
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				if next < 0 {
+					return plain, int64(off), io.ErrUnexpectedEOF
+				}
+				err = ErrInvalidBlock
+			}
+			if onCorrupt == nil {
+				return plain, int64(off), &CorruptRecordError{Offset: int64(off), Err: err}
+			}
+			onCorrupt(int64(off), err)
+			off = next
+			if next < 0 {
+				off = len(buf)
+			}
+			continue
+		}
+
+		plain = append(plain, data...)
+		off = end
+	}
+
+	return plain, int64(off), nil
+}
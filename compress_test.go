@@ -0,0 +1,274 @@
+package timeseries
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+var compressions = []string{CompressionGzip, CompressionZstd, CompressionSnappy}
+
+func storeMetrics(t *testing.T, c *Client[testStruct], base time.Time, batches int, perBatch int) {
+	t.Helper()
+
+	for b := 0; b < batches; b++ {
+		entries := make([]Entry[testStruct], perBatch)
+		for i := range entries {
+			n := b*perBatch + i
+			entries[i] = Entry[testStruct]{
+				Time: base.Add(time.Duration(n) * time.Second),
+				Data: testStruct{SomeString: "cpu", SomeInt: n % 10, SomeFloat: float64(n%100) / 4},
+			}
+		}
+		if err := c.StoreBatch(entries); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_Compression(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	plainDir := t.TempDir()
+	plain, err := Init[testStruct](Options{Path: plainDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeMetrics(t, plain, base, 5, 200)
+	plainInfo, err := os.Stat(filepath.Join(plainDir, "2024", "06", "15", "10.cbor"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, compression := range compressions {
+		t.Run(compression, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			c, err := Init[testStruct](Options{Path: tmpDir, Compression: compression})
+			if err != nil {
+				t.Fatal(err)
+			}
+			storeMetrics(t, c, base, 5, 200)
+
+			path := filepath.Join(tmpDir, "2024", "06", "15", "10"+codecs[compression].ext)
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("expected compressed hour file: %v", err)
+			}
+			if info.Size()*2 > plainInfo.Size() {
+				t.Fatalf("expected %s file to be much smaller than %d bytes, got %d", compression, plainInfo.Size(), info.Size())
+			}
+
+			reopened, err := Init[testStruct](Options{Path: tmpDir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reopened.Opts.Compression != compression {
+				t.Fatalf("expected compression %s to be adopted, got %q", compression, reopened.Opts.Compression)
+			}
+			if !reopened.getCache(base) {
+				t.Fatal("expected compressed file to be cached on init")
+			}
+
+			results, err := reopened.Get(base, base.Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1000 {
+				t.Fatalf("expected 1000 results, got %d", len(results))
+			}
+			for i, result := range results {
+				if result.SomeInt != i%10 {
+					t.Fatalf("expected entry %d to round trip, got %+v", i, result)
+				}
+			}
+
+			if err := reopened.Delete(base, base.Add(499*time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			results, err = reopened.Get(base, base.Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 500 {
+				t.Fatalf("expected 500 results after delete, got %d", len(results))
+			}
+
+			report, err := reopened.Verify(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Files != 1 || report.Entries != 500 || len(report.Issues) != 0 {
+				t.Fatalf("expected a clean report with 500 entries, got %+v", report)
+			}
+		})
+	}
+}
+
+func Test_CompressionMismatch(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := Init[testStruct](Options{Path: tmpDir, Compression: CompressionGzip}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Init[testStruct](Options{Path: tmpDir, Compression: CompressionZstd})
+	if !errors.Is(err, ErrManifestMismatch) {
+		t.Fatalf("expected ErrManifestMismatch, got %v", err)
+	}
+
+	_, err = Init[testStruct](Options{Path: t.TempDir(), Compression: "lz4"})
+	if !errors.Is(err, ErrUnknownCompression) {
+		t.Fatalf("expected ErrUnknownCompression, got %v", err)
+	}
+}
+
+func Test_CompressedTornWrite(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Compression: CompressionZstd})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeMetrics(t, c, base, 1, 10)
+
+	path := c.timeToPath(base)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := info.Size()
+
+	storeMetrics(t, c, base.Add(time.Minute), 1, 10)
+	tearLastRecord(t, path)
+
+	_, err = c.Get(base, base.Add(time.Hour))
+	var tornErr *TornWriteError
+	if !errors.As(err, &tornErr) {
+		t.Fatalf("expected TornWriteError, got %v", err)
+	}
+	if tornErr.Offset != valid {
+		t.Fatalf("expected torn write after the first block at %d, got %d", valid, tornErr.Offset)
+	}
+
+	recovering, err := Init[testStruct](Options{Path: tmpDir, RecoverTornWrites: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := recovering.Get(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 10 {
+		t.Fatalf("expected the 10 entries of the complete block, got %d", len(results))
+	}
+
+	if _, err := recovering.Repair(base); err != nil {
+		t.Fatal(err)
+	}
+	info, err = os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != valid {
+		t.Fatalf("expected file to be truncated to %d bytes, got %d", valid, info.Size())
+	}
+
+	if _, err := c.Get(base, base.Add(time.Hour)); err != nil {
+		t.Fatalf("expected repaired file to be readable, got %v", err)
+	}
+}
+
+func Test_CompressedCorruptBlock(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Compression: CompressionGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeMetrics(t, c, base, 1, 10)
+	path := c.timeToPath(base)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeMetrics(t, c, base.Add(time.Minute), 1, 10)
+
+	flipByte(t, path, info.Size()/2)
+
+	_, err = c.Get(base, base.Add(time.Hour))
+	var corruptErr *CorruptRecordError
+	if !errors.As(err, &corruptErr) {
+		t.Fatalf("expected CorruptRecordError, got %v", err)
+	}
+
+	skipping, err := Init[testStruct](Options{Path: tmpDir, Corruption: CorruptSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := skipping.Get(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 10 || skipping.CorruptRecords() != 1 {
+		t.Fatalf("expected the second block to survive and one corrupt block, got %d results and %d corrupt", len(results), skipping.CorruptRecords())
+	}
+}
+
+func Test_CompressedCorruptBlockLength(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Compression: CompressionGzip, Corruption: CorruptSkip, RecoverTornWrites: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeMetrics(t, c, base, 1, 10)
+	storeMetrics(t, c, base.Add(time.Minute), 1, 10)
+	path := c.timeToPath(base)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipByte(t, path, 2)
+
+	results, err := c.Get(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 10 || c.CorruptRecords() != 1 {
+		t.Fatalf("expected the second block to survive and one corrupt block, got %d results and %d corrupt", len(results), c.CorruptRecords())
+	}
+
+	strict, err := Init[testStruct](Options{Path: tmpDir, Compression: CompressionGzip, RecoverTornWrites: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corruptErr *CorruptRecordError
+	if _, err := strict.Get(base, base.Add(time.Hour)); !errors.As(err, &corruptErr) {
+		t.Fatalf("expected CorruptRecordError, got %v", err)
+	}
+
+	if _, err := c.Repair(base); err != nil {
+		t.Fatal(err)
+	}
+	repaired, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repaired.Size() != info.Size() {
+		t.Fatalf("expected repair to keep all %d bytes, got %d", info.Size(), repaired.Size())
+	}
+}
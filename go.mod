@@ -2,6 +2,10 @@ module github.com/zveinn/timeseries
 
 go 1.25.4
 
-require github.com/fxamacker/cbor/v2 v2.9.0
+require (
+	github.com/fxamacker/cbor/v2 v2.9.0
+	github.com/klauspost/compress v1.18.0
+	go.uber.org/goleak v1.3.0
+)
 
 require github.com/x448/float16 v0.8.4 // indirect
@@ -0,0 +1,451 @@
+package timeseries
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	indexName    = ".timeseries.index"
+	indexVersion = 3
+)
+
+type storeIndex struct {
+	Version  int              `cbor:"1,keyasint"`
+	Bucket   string           `cbor:"2,keyasint"`
+	Location string           `cbor:"3,keyasint"`
+	Ext      string           `cbor:"4,keyasint"`
+	Dirs     map[string]int64 `cbor:"5,keyasint"`
+	Buckets  []indexedBucket  `cbor:"6,keyasint"`
+}
+
+type indexedBucket struct {
+	_     struct{} `cbor:",toarray"`
+	Start int64
+	Size  int64
+	Count int64
+	Min   int64
+	Max   int64
+	Files [2]fileID
+}
+
+func (b indexedBucket) stats() bucketStats {
+	return bucketStats{Size: b.Size, Count: b.Count, Min: b.Min, Max: b.Max, Files: b.Files}
+}
+
+func (c *Client[T]) indexPath() string {
+	return filepath.Join(c.Opts.Path, indexName)
+}
+
+func (c *Client[T]) indexed() bool {
+	return c.Opts.Index
+}
+
+func (c *Client[T]) loadCache() error {
+	if c.indexed() {
+		loaded, err := c.loadIndex()
+		if err != nil || loaded {
+			return err
+		}
+	}
+
+	if err := c.buildCache(); err != nil {
+		return err
+	}
+
+	if !c.indexed() {
+		return nil
+	}
+
+	c.indexMu.Lock()
+	c.indexDirty = true
+	c.indexMu.Unlock()
+	return c.saveIndex()
+}
+
+func (c *Client[T]) readIndex() (*storeIndex, error) {
+	buf, err := os.ReadFile(c.indexPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	idx := new(storeIndex)
+	if err := cbor.Unmarshal(buf, idx); err != nil {
+		return nil, nil
+	}
+
+	if idx.Version != indexVersion || idx.Bucket != c.bucket().String() ||
+		idx.Location != c.location().String() || idx.Ext != c.ext() {
+		return nil, nil
+	}
+
+	if idx.Dirs == nil {
+		idx.Dirs = make(map[string]int64)
+	}
+	return idx, nil
+}
+
+func (c *Client[T]) loadIndex() (bool, error) {
+	idx, err := c.readIndex()
+	if err != nil || idx == nil {
+		return false, err
+	}
+
+	fresh, err := c.indexFresh(idx.Dirs)
+	if err != nil || !fresh {
+		return false, err
+	}
+
+	c.mu.Lock()
+	for _, b := range idx.Buckets {
+		c.setStatsLocked(time.Unix(b.Start, 0), b.stats())
+	}
+	for dir, mtime := range idx.Dirs {
+		c.dirTimes[filepath.Join(c.Opts.Path, dir)] = mtime
+	}
+	c.mu.Unlock()
+
+	c.indexDirs = idx.Dirs
+	return true, nil
+}
+
+func (c *Client[T]) openLazyIndex() error {
+	idx, err := c.readIndex()
+	if err != nil || idx == nil {
+		return err
+	}
+
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+
+	c.indexDirs = idx.Dirs
+	c.indexYears = make(map[int][]indexedBucket)
+	for _, b := range idx.Buckets {
+		year, _ := c.cacheKey(time.Unix(b.Start, 0))
+		c.indexYears[year] = append(c.indexYears[year], b)
+	}
+	return nil
+}
+
+func (c *Client[T]) loadIndexedYear(year int, root string) error {
+	rel := filepath.Base(root)
+	inYear := func(dir string) bool {
+		return dir == rel || strings.HasPrefix(dir, rel+string(filepath.Separator))
+	}
+
+	buckets := c.indexYears[year]
+	delete(c.indexYears, year)
+
+	if _, known := c.indexDirs[rel]; known {
+		fresh := true
+		for dir, mtime := range c.indexDirs {
+			if !inYear(dir) {
+				continue
+			}
+			fi, err := os.Stat(filepath.Join(c.Opts.Path, dir))
+			if err != nil || fi.ModTime().UnixNano() != mtime {
+				fresh = false
+				break
+			}
+		}
+
+		if fresh {
+			c.mu.Lock()
+			for _, b := range buckets {
+				c.setStatsLocked(time.Unix(b.Start, 0), b.stats())
+			}
+			for dir, mtime := range c.indexDirs {
+				if inYear(dir) {
+					c.dirTimes[filepath.Join(c.Opts.Path, dir)] = mtime
+				}
+			}
+			c.mu.Unlock()
+			return nil
+		}
+	}
+
+	for dir := range c.indexDirs {
+		if inYear(dir) {
+			delete(c.indexDirs, dir)
+			c.indexDirty = true
+		}
+	}
+
+	files := make(map[string]os.FileInfo)
+	err := c.scanTree(root, files)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.reconcileStats(c.bucketStamps(files))
+	c.indexDirty = true
+	return nil
+}
+
+func (c *Client[T]) indexFresh(dirs map[string]int64) (bool, error) {
+	entries, err := os.ReadDir(c.Opts.Path)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if _, ok := dirs[entry.Name()]; entry.IsDir() && !ok {
+			return false, nil
+		}
+	}
+
+	for dir, mtime := range dirs {
+		fi, err := os.Stat(filepath.Join(c.Opts.Path, dir))
+		if err != nil || fi.ModTime().UnixNano() != mtime {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (c *Client[T]) scanTree(root string, files map[string]os.FileInfo) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != filepath.Clean(c.Opts.Path) {
+				c.seenDir(path, info.ModTime().UnixNano())
+				if rel, err := filepath.Rel(c.Opts.Path, path); err == nil && c.indexed() {
+					c.indexDirs[rel] = info.ModTime().UnixNano()
+				}
+			}
+			return nil
+		}
+
+		if c.isDataFile(path) {
+			files[path] = info
+		}
+		return nil
+	})
+}
+
+func (c *Client[T]) bucketStamps(files map[string]os.FileInfo) map[int64]bucketStats {
+	stamps := make(map[int64]bucketStats)
+	for path, info := range files {
+		t, err := c.parsePathToTime(path)
+		if err != nil {
+			continue
+		}
+		stamp, ok := stamps[t.Unix()]
+		if !ok {
+			stamp.Count = -1
+		}
+		stamp.addFile(path, info)
+		stamps[t.Unix()] = stamp
+	}
+	return stamps
+}
+
+func (c *Client[T]) reconcileStats(stamps map[int64]bucketStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for start, stamp := range stamps {
+		bucket := time.Unix(start, 0)
+		if s, ok := c.stats[start]; ok && s.current(stamp) && c.cachedLocked(bucket) {
+			continue
+		}
+		c.setStatsLocked(bucket, stamp)
+	}
+}
+
+func (c *Client[T]) cachedLocked(t time.Time) bool {
+	y, i := c.cacheKey(t)
+	return c.presentLocked(y, i)
+}
+
+func (c *Client[T]) indexChanged(bucket time.Time) {
+	if !c.indexed() || c.Opts.ReadOnly {
+		return
+	}
+
+	rel, err := filepath.Rel(c.Opts.Path, filepath.Dir(c.timeToPath(bucket)))
+	if err != nil {
+		return
+	}
+
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+
+	if !c.indexDirty {
+		os.Remove(c.indexPath())
+		c.indexDirty = true
+	}
+	c.indexTouched[rel] = true
+}
+
+func (c *Client[T]) saveIndex() error {
+	if !c.indexed() || c.Opts.ReadOnly || c.Opts.Path == "" {
+		return nil
+	}
+
+	c.indexMu.Lock()
+	defer c.indexMu.Unlock()
+
+	if !c.indexDirty {
+		return nil
+	}
+	if _, err := os.Stat(c.Opts.Path); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := c.syncIndexDirs(); err != nil {
+		return err
+	}
+
+	idx := storeIndex{
+		Version:  indexVersion,
+		Bucket:   c.bucket().String(),
+		Location: c.location().String(),
+		Ext:      c.ext(),
+		Dirs:     c.indexDirs,
+	}
+
+	for _, buckets := range c.indexYears {
+		idx.Buckets = append(idx.Buckets, buckets...)
+	}
+
+	c.mu.RLock()
+	for _, bucket := range c.cachedBucketsLocked() {
+		s, ok := c.stats[bucket.Unix()]
+		if !ok {
+			s = unknownStats
+		}
+		idx.Buckets = append(idx.Buckets, indexedBucket{
+			Start: bucket.Unix(),
+			Size:  s.Size,
+			Count: s.Count,
+			Min:   s.Min,
+			Max:   s.Max,
+			Files: s.Files,
+		})
+	}
+	c.mu.RUnlock()
+
+	buf, err := cbor.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.replaceFile(c.indexPath(), buf); err != nil {
+		return err
+	}
+
+	c.indexDirty = false
+	clear(c.indexTouched)
+	return nil
+}
+
+func (c *Client[T]) syncIndexDirs() error {
+	touched := make(map[string]bool)
+	for rel := range c.indexTouched {
+		for ; rel != "." && rel != string(filepath.Separator); rel = filepath.Dir(rel) {
+			touched[rel] = true
+		}
+	}
+	if len(touched) == 0 {
+		return nil
+	}
+
+	depth := func(rel string) int {
+		return strings.Count(rel, string(filepath.Separator))
+	}
+	dirs := make([]string, 0, len(touched))
+	for rel := range touched {
+		dirs = append(dirs, rel)
+	}
+	slices.SortFunc(dirs, func(a, b string) int {
+		return cmp.Or(cmp.Compare(depth(a), depth(b)), cmp.Compare(a, b))
+	})
+
+	cached := make(map[string][]int64)
+	for _, bucket := range c.cachedBuckets() {
+		rel, err := filepath.Rel(c.Opts.Path, filepath.Dir(c.timeToPath(bucket)))
+		if err == nil && touched[rel] {
+			cached[rel] = append(cached[rel], bucket.Unix())
+		}
+	}
+
+	files := make(map[string]os.FileInfo)
+	for _, rel := range dirs {
+		dir := filepath.Join(c.Opts.Path, rel)
+		fi, err := os.Stat(dir)
+		if os.IsNotExist(err) {
+			for known := range c.indexDirs {
+				if known == rel || strings.HasPrefix(known, rel+string(filepath.Separator)) {
+					delete(c.indexDirs, known)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if _, ok := c.indexDirs[filepath.Join(rel, entry.Name())]; !ok {
+					if err := c.scanTree(path, files); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if !c.isDataFile(path) {
+				continue
+			}
+			info, err := entry.Info()
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			files[path] = info
+		}
+
+		c.indexDirs[rel] = fi.ModTime().UnixNano()
+	}
+
+	stamps := c.bucketStamps(files)
+
+	c.mu.Lock()
+	for _, starts := range cached {
+		for _, start := range starts {
+			if _, ok := stamps[start]; !ok {
+				y, i := c.cacheKey(time.Unix(start, 0))
+				c.clearPresentLocked(y, i)
+				delete(c.stats, start)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	c.reconcileStats(stamps)
+	return nil
+}
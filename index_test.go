@@ -0,0 +1,155 @@
+package timeseries
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_IndexReload(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Index: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := range 6 {
+		if err := c.Store(base.Add(time.Duration(i)*20*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, indexName)); !os.IsNotExist(err) {
+		t.Fatal("expected the index to be removed while the client has unsaved changes")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, indexName)); err != nil {
+		t.Fatalf("expected the index to be written on close: %v", err)
+	}
+
+	reopened, err := Init[testStruct](Options{Path: tmpDir, Index: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	for hour := range 2 {
+		bucket := base.Add(time.Duration(hour) * time.Hour)
+		if !reopened.getCache(bucket) {
+			t.Fatalf("expected %s to be loaded from the index", bucket)
+		}
+
+		expected, err := reopened.bucketStamp(bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected.Count, expected.Min, expected.Max = 3, bucket.Unix(), bucket.Add(40*time.Minute).Unix()
+		if s := reopened.stats[bucket.Unix()]; s != expected {
+			t.Fatalf("expected stats %+v for %s, got %+v", expected, bucket, s)
+		}
+	}
+}
+
+func Test_IndexRebuildsWhenStale(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Index: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Store(base, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ts := range []time.Time{base.Add(time.Hour), base.AddDate(0, 0, 1), base.AddDate(1, 0, 0)} {
+		if err := other.Store(ts, testStruct{SomeInt: 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := other.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Init[testStruct](Options{Path: tmpDir, Index: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	for _, bucket := range []time.Time{base, base.Add(time.Hour), base.AddDate(0, 0, 1), base.AddDate(1, 0, 0)} {
+		if !reopened.getCache(bucket) {
+			t.Fatalf("expected %s to be found by the rebuild", bucket)
+		}
+	}
+	if s := reopened.stats[base.Unix()]; s.Count != -1 {
+		t.Fatalf("expected rebuilt stats to have an unknown count, got %+v", s)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, indexName), []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	corrupt, err := Init[testStruct](Options{Path: tmpDir, Index: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer corrupt.Close()
+	if !corrupt.getCache(base.AddDate(1, 0, 0)) {
+		t.Fatal("expected a corrupt index to be rebuilt")
+	}
+}
+
+func Test_IndexTracksDeletes(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Index: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 4 {
+		if err := c.Store(base.Add(time.Duration(i)*30*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Delete(base.Add(time.Hour), base.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(base, base.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Init[testStruct](Options{Path: tmpDir, Index: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	if reopened.getCache(base.Add(time.Hour)) {
+		t.Fatal("expected the deleted hour to be absent from the index")
+	}
+	expected, err := reopened.bucketStamp(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining := base.Add(30 * time.Minute).Unix()
+	expected.Count, expected.Min, expected.Max = 1, remaining, remaining
+	if s := reopened.stats[base.Unix()]; s != expected {
+		t.Fatalf("expected stats %+v after a partial delete, got %+v", expected, s)
+	}
+}
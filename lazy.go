@@ -0,0 +1,83 @@
+package timeseries
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+func (c *Client[T]) loadYear(t time.Time) error {
+	if !c.Opts.LazyCache || c.Opts.Path == "" {
+		return nil
+	}
+
+	year := t.In(c.location()).Year()
+	if c.yearLoaded(year) {
+		return nil
+	}
+
+	c.yearMu.Lock()
+	defer c.yearMu.Unlock()
+
+	if c.yearLoaded(year) {
+		return nil
+	}
+
+	root := filepath.Join(c.Opts.Path, fmt.Sprintf("%04d", year))
+	if c.indexed() {
+		c.indexMu.Lock()
+		err := c.loadIndexedYear(year, root)
+		c.indexMu.Unlock()
+		if err != nil {
+			return err
+		}
+	} else {
+		files := make(map[string]os.FileInfo)
+		err := c.scanTree(root, files)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		c.reconcileStats(c.bucketStamps(files))
+	}
+
+	c.mu.Lock()
+	if c.years == nil {
+		c.years = make(map[int]bool)
+	}
+	c.years[year] = true
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client[T]) yearLoaded(year int) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.years[year]
+}
+
+func (c *Client[T]) loadYears() error {
+	if !c.Opts.LazyCache || c.Opts.Path == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(c.Opts.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		year, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		if err := c.loadYear(time.Date(year, 6, 1, 0, 0, 0, 0, c.location())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
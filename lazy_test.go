@@ -0,0 +1,150 @@
+package timeseries
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_LazyCache(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	years := []int{2020, 2022, 2024}
+	for _, year := range years {
+		if err := c.Store(time.Date(year, 3, 1, 12, 0, 0, 0, time.UTC), testStruct{SomeInt: year}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lazy, err := Init[testStruct](Options{Path: tmpDir, LazyCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lazy.Close()
+
+	if len(lazy.Cache) != 0 {
+		t.Fatalf("expected no years to be loaded on init, got %d", len(lazy.Cache))
+	}
+
+	results, err := lazy.Get(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SomeInt != 2024 {
+		t.Fatalf("expected the 2024 entry, got %v", results)
+	}
+
+	if len(lazy.Cache) != 1 || len(lazy.cachedBuckets()) != 1 {
+		t.Fatalf("expected only 2024 to be loaded, got %v", lazy.Cache)
+	}
+
+	bucket := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := lazy.Store(bucket.Add(time.Minute), testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	size, err := lazy.bucketSize(bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := lazy.stats[bucket.Unix()]; s.Size != size {
+		t.Fatalf("expected a store into an unloaded year to load it first, got size %d want %d", s.Size, size)
+	}
+
+	report, err := lazy.Compact(context.Background(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Sealed) != len(years) {
+		t.Fatalf("expected compaction to load and seal every year, got %v", report.Sealed)
+	}
+
+	var loaded []int
+	for year := range lazy.Cache {
+		loaded = append(loaded, year)
+	}
+	slices.Sort(loaded)
+	if !slices.Equal(loaded, years) {
+		t.Fatalf("expected years %v to be loaded, got %v", years, loaded)
+	}
+}
+
+func Test_LazyCacheWithIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Index: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	years := []int{2020, 2022, 2024}
+	for _, year := range years {
+		if err := c.Store(time.Date(year, 3, 1, 12, 0, 0, 0, time.UTC), testStruct{SomeInt: year}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lazy, err := Init[testStruct](Options{Path: tmpDir, Index: true, LazyCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lazy.Cache) != 0 {
+		t.Fatalf("expected no years to be loaded on init, got %d", len(lazy.Cache))
+	}
+
+	indexed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	results, err := lazy.Get(indexed, indexed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected the 2024 entry, got %v", results)
+	}
+	if s, ok := lazy.cachedStats(indexed); !ok || s.Count != 1 {
+		t.Fatalf("expected 2024 to be loaded from the index with its count, got %+v", s)
+	}
+
+	other, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := other.Store(added, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = lazy.Get(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected a stale year to be walked, got %v", results)
+	}
+	if len(lazy.Cache) != 2 {
+		t.Fatalf("expected only 2022 and 2024 to be loaded, got %v", lazy.Cache)
+	}
+	if err := lazy.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Init[testStruct](Options{Path: tmpDir, Index: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	unloaded := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	if s, ok := reopened.cachedStats(unloaded); !ok || s.Count != 1 {
+		t.Fatalf("expected the index to keep the year the lazy client never loaded, got %+v", s)
+	}
+	if !reopened.getCache(added) {
+		t.Fatal("expected the index to include the bucket found by the lazy walk")
+	}
+}
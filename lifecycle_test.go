@@ -0,0 +1,120 @@
+package timeseries
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+func Test_CloseStopsBackgroundGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	c, err := Init[testStruct](Options{
+		Path:              t.TempDir(),
+		Debug:             true,
+		Retention:         time.Hour,
+		RetentionInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.NewWriter(WriterOptions{FlushInterval: time.Millisecond})
+	c.NewWriter(WriterOptions{FlushInterval: time.Hour})
+
+	time.Sleep(10 * time.Millisecond)
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_CloseFlushesWriters(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	tmpDir := t.TempDir()
+	c, err := Init[testStruct](Options{Path: tmpDir, Sync: SyncEveryN, SyncEvery: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{FlushInterval: time.Hour})
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		if err := w.Store(baseTime.Add(time.Duration(i)*time.Second), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.syncs.Load() != 1 {
+		t.Fatalf("expected pending writes to be synced on close, got %d syncs", c.syncs.Load())
+	}
+
+	if err := w.Store(baseTime, testStruct{}); !errors.Is(err, ErrWriterClosed) {
+		t.Fatalf("expected ErrWriterClosed, got %v", err)
+	}
+
+	reopened, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	results, err := reopened.Get(baseTime, baseTime.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 10 {
+		t.Fatalf("expected 10 results after close, got %d", len(results))
+	}
+}
+
+func Test_ClosedClientReturnsErrClosed(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("expected second Close to be a no-op, got %v", err)
+	}
+
+	now := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	checks := map[string]error{
+		"Store":      c.Store(now, testStruct{}),
+		"StoreBatch": c.StoreBatch([]Entry[testStruct]{{Time: now}}),
+		"Find": c.Find(now, now, func(time.Time, testStruct) bool {
+			return true
+		}),
+		"Delete":  c.Delete(now, now),
+		"Refresh": c.Refresh(now, now),
+		"Sync":    c.Sync(),
+	}
+
+	_, checks["Get"] = c.Get(now, now)
+	_, checks["DeleteWhere"] = c.DeleteWhere(now, now, func(time.Time, testStruct) bool {
+		return true
+	})
+	_, checks["Repair"] = c.Repair(now)
+	_, checks["Verify"] = c.Verify(ctx)
+	_, checks["EnforceRetention"] = c.EnforceRetention()
+
+	for name, err := range checks {
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("%s: expected ErrClosed, got %v", name, err)
+		}
+	}
+}
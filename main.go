@@ -1,22 +1,65 @@
 package timeseries
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+)
+
+type SyncPolicy int
 
-	"github.com/fxamacker/cbor/v2"
+const (
+	SyncNever SyncPolicy = iota
+	SyncAlways
+	SyncEveryN
+	SyncPeriodic
 )
 
 type Options struct {
-	Debug       bool
-	PrintMemory bool
-	Path        string
+	Debug        bool
+	PrintMemory  bool
+	Path         string
+	Bucket       time.Duration
+	Location     *time.Location
+	Sync         SyncPolicy
+	SyncEvery    int
+	SyncInterval time.Duration
+	Index        bool
+	LazyCache    bool
+	ReadOnly     bool
+
+	RecoverTornWrites bool
+	OnTornWrite       func(err *TornWriteError)
+
+	ReadWorkers int
+
+	Checksums   bool
+	Corruption  CorruptionPolicy
+	Compression string
+
+	TypeCheck bool
+	Migrate   func(m *Manifest, to int) error
+
+	Retention         time.Duration
+	MaxBytes          int64
+	RetentionInterval time.Duration
+	OnRetention       func(report *RetentionReport, err error)
+
+	SealAfter       time.Duration
+	CompactInterval time.Duration
+	OnCompact       func(report *CompactReport, err error)
 }
 
 type Entry[T any] struct {
@@ -24,72 +67,387 @@ type Entry[T any] struct {
 	Data T
 }
 
+var (
+	ErrClosed        = errors.New("client is closed")
+	ErrReadOnly      = errors.New("client is read-only")
+	ErrInvalidBucket = errors.New("bucket must be a whole number of minutes that divides a day")
+)
+
+type TornWriteError struct {
+	Path   string
+	Offset int64
+	Size   int64
+}
+
+func (e *TornWriteError) Error() string {
+	return fmt.Sprintf("torn write in %s: %d trailing bytes after offset %d", e.Path, e.Size-e.Offset, e.Offset)
+}
+
+func (e *TornWriteError) Unwrap() error {
+	return io.ErrUnexpectedEOF
+}
+
+type BatchError struct {
+	Errs []error
+}
+
+func (e *BatchError) Error() string {
+	var first error
+	failed := 0
+	for _, err := range e.Errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d entries failed: %v", failed, len(e.Errs), first)
+}
+
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
 type Client[T any] struct {
-	Cache map[int]*[12][31][24]struct{}
+	Cache map[int][]uint64
 	Opts  Options
+
+	mu    sync.RWMutex
+	dirMu sync.RWMutex
+	files sync.Map
+	stats map[int64]bucketStats
+
+	indexMu      sync.Mutex
+	indexDirs    map[string]int64
+	indexTouched map[string]bool
+	indexDirty   bool
+	indexYears   map[int][]indexedBucket
+
+	yearMu      sync.Mutex
+	years       map[int]bool
+	cacheLoaded bool
+	dirTimes    map[string]int64
+
+	syncMu   sync.Mutex
+	dirty    map[string]bool
+	writes   int
+	lastSync time.Time
+	syncs    atomic.Int64
+	synced   func(path string)
+
+	corrupt    atomic.Int64
+	manifestOK atomic.Bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+	closed    atomic.Bool
+	wg        sync.WaitGroup
+
+	writersMu sync.Mutex
+	writers   map[*Writer[T]]struct{}
 }
 
 func Init[T any](opts Options) (client *Client[T], err error) {
+	if opts.Bucket != 0 && !validBucket(opts.Bucket) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBucket, opts.Bucket)
+	}
+
+	if opts.Compression != "" && codecs[opts.Compression] == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCompression, opts.Compression)
+	}
+
+	if opts.ReadOnly && (opts.Retention > 0 || opts.MaxBytes > 0 || opts.SealAfter > 0) {
+		return nil, fmt.Errorf("%w: retention and compaction modify the store", ErrReadOnly)
+	}
+
 	client = new(Client[T])
 	client.Opts = opts
-	client.Cache = make(map[int]*[12][31][24]struct{})
+	client.Cache = make(map[int][]uint64)
+	client.stats = make(map[int64]bucketStats)
+	client.indexDirs = make(map[string]int64)
+	client.indexTouched = make(map[string]bool)
+	client.dirTimes = make(map[string]int64)
+	client.done = make(chan struct{})
 
 	if opts.Path != "" {
-		err = client.buildCache()
-		if err != nil {
-			return nil, err
+		if _, statErr := os.Stat(opts.Path); statErr == nil {
+			err = client.ensureManifest(true)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if client.Opts.Bucket == 0 {
+		client.Opts.Bucket = time.Hour
+	}
+
+	if opts.Path != "" {
+		if !opts.LazyCache {
+			err = client.loadCache()
+			if err != nil {
+				return nil, err
+			}
+			client.cacheLoaded = true
+		} else if client.indexed() {
+			err = client.openLazyIndex()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if opts.Retention > 0 || opts.MaxBytes > 0 {
+			client.wg.Add(1)
+			go client.retentionLoop()
+		}
+
+		if opts.SealAfter > 0 {
+			client.wg.Add(1)
+			go client.compactLoop()
+		}
+
+		if opts.Sync == SyncPeriodic && opts.SyncInterval > 0 {
+			client.wg.Add(1)
+			go client.syncLoop()
 		}
 	}
 
 	if opts.Debug {
-		go initDebugPrintLoop(opts)
+		client.wg.Add(1)
+		go client.debugPrintLoop()
 	}
 
 	return
 }
 
+func (c *Client[T]) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		if c.done != nil {
+			close(c.done)
+		}
+		c.wg.Wait()
+
+		c.writersMu.Lock()
+		writers := c.writers
+		c.writers = nil
+		c.writersMu.Unlock()
+
+		var errs []error
+		for w := range writers {
+			errs = append(errs, w.Close())
+		}
+
+		if c.Opts.Sync != SyncNever {
+			c.syncMu.Lock()
+			errs = append(errs, c.syncDirtyLocked())
+			c.syncMu.Unlock()
+		}
+
+		errs = append(errs, c.saveIndex())
+
+		c.files.Clear()
+		err = errors.Join(errs...)
+	})
+	return err
+}
+
+func (c *Client[T]) writable() error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	if c.Opts.ReadOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
 func (c *Client[T]) buildCache() error {
 	if _, err := os.Stat(c.Opts.Path); os.IsNotExist(err) {
 		return nil
 	}
 
-	return filepath.Walk(c.Opts.Path, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	files := make(map[string]os.FileInfo)
+	if err := c.scanTree(c.Opts.Path, files); err != nil {
+		return err
+	}
+
+	c.reconcileStats(c.bucketStamps(files))
+	return nil
+}
+
+func (c *Client[T]) Refresh(from time.Time, to time.Time) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	first, last := c.bucketRange(from, to)
+	from = first.In(c.location())
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, c.location())
+	files := make(map[string]os.FileInfo)
+	for day := fromDay; !day.After(to); day = day.AddDate(0, 0, 1) {
+		root := filepath.Dir(c.timeToPath(day))
+		if c.layoutDepth() == 5 {
+			root = filepath.Dir(root)
 		}
 
-		if info.IsDir() {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !c.isDataFile(path) {
+				return nil
+			}
+			t, parseErr := c.parsePathToTime(path)
+			if parseErr != nil || t.Before(first) || t.After(last) {
+				return nil
+			}
+			if !c.getCache(t) {
+				files[path] = info
+			}
 			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return err
 		}
+	}
 
-		if filepath.Ext(path) != ".cbor" {
-			return nil
+	found := c.bucketStamps(files)
+	c.reconcileStats(found)
+	for start := range found {
+		c.indexChanged(time.Unix(start, 0))
+	}
+
+	return nil
+}
+
+func validBucket(bucket time.Duration) bool {
+	return bucket >= time.Minute && bucket%time.Minute == 0 && (24*time.Hour)%bucket == 0
+}
+
+func (c *Client[T]) bucket() time.Duration {
+	if c.Opts.Bucket == 0 {
+		return time.Hour
+	}
+	return c.Opts.Bucket
+}
+
+func (c *Client[T]) location() *time.Location {
+	if c.Opts.Location == nil {
+		return time.UTC
+	}
+	return c.Opts.Location
+}
+
+func (c *Client[T]) bucketMinutes() int {
+	return int(c.bucket() / time.Minute)
+}
+
+func (c *Client[T]) bucketStart(t time.Time) time.Time {
+	t = t.In(c.location())
+	minute := (t.Hour()*60 + t.Minute()) / c.bucketMinutes() * c.bucketMinutes()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, minute, 0, 0, c.location())
+}
+
+func (c *Client[T]) nextBucket(bucket time.Time) time.Time {
+	b := bucket.In(c.location())
+	next := time.Date(b.Year(), b.Month(), b.Day(), b.Hour(), b.Minute()+c.bucketMinutes(), 0, 0, c.location())
+	if !next.After(bucket) {
+		return bucket.Add(c.bucket())
+	}
+	return next
+}
+
+func (c *Client[T]) bucketEnd(bucket time.Time) time.Time {
+	last := c.nextBucket(bucket).Add(-time.Nanosecond).In(c.location())
+	_, offset := last.Zone()
+	if _, end := last.ZoneBounds(); !end.IsZero() {
+		_, after := end.In(c.location()).Zone()
+		if repeat := time.Duration(offset-after) * time.Second; repeat > 0 && !last.Add(repeat).Before(end) {
+			return last.Add(repeat)
 		}
+	}
+	return last
+}
 
-		t, parseErr := c.parsePathToTime(path)
-		if parseErr != nil {
-			return nil
+func (c *Client[T]) bucketRange(from time.Time, to time.Time) (time.Time, time.Time) {
+	first, last := c.bucketStart(from), c.bucketStart(to)
+	if _, end := from.In(c.location()).ZoneBounds(); !end.IsZero() && !end.After(to) && c.bucketStart(end).Before(first) {
+		first = c.bucketStart(end)
+	}
+	if start, _ := to.In(c.location()).ZoneBounds(); !start.IsZero() && start.After(from) {
+		if before := c.bucketStart(start.Add(-time.Nanosecond)); before.After(last) {
+			last = before
 		}
+	}
+	return first, last
+}
 
-		c.setCache(t)
-		return nil
-	})
+func (c *Client[T]) prevBucket(bucket time.Time) time.Time {
+	return c.bucketStart(bucket.Add(-time.Nanosecond))
+}
+
+func (c *Client[T]) cacheKey(t time.Time) (int, int) {
+	t = t.In(c.location())
+	perDay := 24 * 60 / c.bucketMinutes()
+	return t.Year(), (t.YearDay()-1)*perDay + (t.Hour()*60+t.Minute())/c.bucketMinutes()
+}
+
+func (c *Client[T]) cacheBucket(year int, index int) time.Time {
+	perDay := 24 * 60 / c.bucketMinutes()
+	return time.Date(year, 1, 1+index/perDay, 0, index%perDay*c.bucketMinutes(), 0, 0, c.location())
 }
 
 func (c *Client[T]) setCache(t time.Time) {
-	y, m, d, h := t.Year(), int(t.Month())-1, t.Day()-1, t.Hour()
-	if c.Cache[y] == nil {
-		c.Cache[y] = new([12][31][24]struct{})
-	}
-	c.Cache[y][m][d][h] = struct{}{}
+	y, i := c.cacheKey(t)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setPresentLocked(y, i)
+}
+
+func (c *Client[T]) clearCache(t time.Time) {
+	y, i := c.cacheKey(t)
+	c.mu.Lock()
+	c.clearPresentLocked(y, i)
+	delete(c.stats, t.Unix())
+	c.mu.Unlock()
+
+	c.indexChanged(t)
 }
 
 func (c *Client[T]) getCache(t time.Time) bool {
-	y, m, d, h := t.Year(), int(t.Month())-1, t.Day()-1, t.Hour()
-	if c.Cache[y] == nil {
-		return false
+	c.loadYear(t)
+	y, i := c.cacheKey(t)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.presentLocked(y, i)
+}
+
+func (c *Client[T]) fileLock(path string) *sync.RWMutex {
+	if l, ok := c.files.Load(path); ok {
+		return l.(*sync.RWMutex)
+	}
+	l, _ := c.files.LoadOrStore(path, new(sync.RWMutex))
+	return l.(*sync.RWMutex)
+}
+
+func (c *Client[T]) layoutDepth() int {
+	switch {
+	case c.bucket() == 24*time.Hour:
+		return 3
+	case c.bucket()%time.Hour == 0:
+		return 4
+	default:
+		return 5
 	}
-	return c.Cache[y][m][d][h] == struct{}{}
 }
 
 func (c *Client[T]) parsePathToTime(path string) (time.Time, error) {
@@ -98,62 +456,89 @@ func (c *Client[T]) parsePathToTime(path string) (time.Time, error) {
 		return time.Time{}, err
 	}
 
-	dir := filepath.Dir(rel)
-	parts := filepath.SplitList(dir)
-	if len(parts) == 1 {
-		parts = []string{}
-		current := dir
-		for current != "." && current != "" {
-			parts = append([]string{filepath.Base(current)}, parts...)
-			current = filepath.Dir(current)
-		}
+	name := filepath.Base(rel)
+	if !c.isDataFile(name) {
+		return time.Time{}, errors.New("invalid file extension")
 	}
 
-	if len(parts) != 3 {
+	parts := strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/")
+	parts = append(parts, strings.TrimSuffix(strings.TrimSuffix(name, c.ext()), sealedExt))
+	if len(parts) != c.layoutDepth() {
 		return time.Time{}, errors.New("invalid path structure")
 	}
 
-	year, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return time.Time{}, err
+	values := []int{0, 1, 1, 0, 0}
+	for i, part := range parts {
+		values[i], err = strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, err
+		}
 	}
 
-	month, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return time.Time{}, err
+	t := time.Date(values[0], time.Month(values[1]), values[2], values[3], values[4], 0, 0, c.location())
+	if t.Year() != values[0] || int(t.Month()) != values[1] || t.Day() != values[2] ||
+		t.Hour() != values[3] || t.Minute() != values[4] {
+		return time.Time{}, errors.New("invalid date in path")
 	}
 
-	day, err := strconv.Atoi(parts[2])
-	if err != nil {
-		return time.Time{}, err
+	if !t.Equal(c.bucketStart(t)) {
+		return time.Time{}, errors.New("path is not aligned to the bucket size")
 	}
 
-	filename := filepath.Base(path)
-	hourStr := filename[:len(filename)-5]
-	hour, err := strconv.Atoi(hourStr)
-	if err != nil {
-		return time.Time{}, err
-	}
+	return t, nil
+}
+
+func (c *Client[T]) isDataFile(path string) bool {
+	return strings.HasSuffix(path, c.ext()) || strings.HasSuffix(path, sealedExt)
+}
 
-	return time.Date(year, time.Month(month), day, hour, 0, 0, 0, time.UTC), nil
+func (c *Client[T]) onDisk(bucket time.Time) bool {
+	for _, path := range []string{c.timeToPath(bucket), c.sealedPath(bucket)} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Client[T]) timeToPath(t time.Time) string {
-	return filepath.Join(
-		c.Opts.Path,
-		fmt.Sprintf("%04d", t.Year()),
-		fmt.Sprintf("%02d", int(t.Month())),
-		fmt.Sprintf("%02d", t.Day()),
-		fmt.Sprintf("%02d.cbor", t.Hour()),
-	)
+	t = t.In(c.location())
+	year := fmt.Sprintf("%04d", t.Year())
+	month := fmt.Sprintf("%02d", int(t.Month()))
+
+	switch c.layoutDepth() {
+	case 3:
+		return filepath.Join(c.Opts.Path, year, month, fmt.Sprintf("%02d", t.Day())+c.ext())
+	case 4:
+		return filepath.Join(c.Opts.Path, year, month, fmt.Sprintf("%02d", t.Day()), fmt.Sprintf("%02d", t.Hour())+c.ext())
+	default:
+		return filepath.Join(
+			c.Opts.Path,
+			year,
+			month,
+			fmt.Sprintf("%02d", t.Day()),
+			fmt.Sprintf("%02d", t.Hour()),
+			fmt.Sprintf("%02d", t.Minute())+c.ext(),
+		)
+	}
 }
 
 func (c *Client[T]) Store(date time.Time, data T) error {
-	truncated := date.Truncate(time.Hour)
-	path := c.timeToPath(truncated)
+	return c.StoreContext(context.Background(), date, data)
+}
 
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+func (c *Client[T]) StoreContext(ctx context.Context, date time.Time, data T) error {
+	if err := c.writable(); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	truncated := c.bucketStart(date)
+	path := c.timeToPath(truncated)
+	if err := c.loadYear(truncated); err != nil {
 		return err
 	}
 
@@ -162,125 +547,980 @@ func (c *Client[T]) Store(date time.Time, data T) error {
 		Data: data,
 	}
 
-	encoded, err := cbor.Marshal(entry)
-	if err != nil {
+	var buf bytes.Buffer
+	if err := newRecordEncoder[T](&buf, c.Opts.Checksums).Encode(entry); err != nil {
 		return err
 	}
 
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	n, err := c.appendFile(path, buf.Bytes())
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	n, err := f.Write(encoded)
-	if err != nil {
+	added := bucketStats{Size: n}
+	added.add(date)
+	c.addStats(truncated, added)
+
+	return nil
+}
+
+func (c *Client[T]) StoreBatch(entries []Entry[T]) error {
+	return c.StoreBatchContext(context.Background(), entries)
+}
+
+func (c *Client[T]) StoreBatchContext(ctx context.Context, entries []Entry[T]) error {
+	if err := c.writable(); err != nil {
 		return err
 	}
 
-	if n != len(encoded) {
-		return errors.New("write verification failed: bytes written != encoded length")
-	}
+	return c.storeBatch(ctx, entries, nil)
+}
 
-	c.setCache(truncated)
+func (c *Client[T]) storeBatch(ctx context.Context, entries []Entry[T], encoded [][]byte) error {
+	type bucket struct {
+		start   time.Time
+		buf     bytes.Buffer
+		enc     *recordEncoder[T]
+		indexes []int
+		stats   bucketStats
+	}
 
-	return nil
-}
+	var order []string
+	buckets := make(map[string]*bucket)
+	errs := make([]error, len(entries))
+	failed := false
 
-func (c *Client[T]) Get(from time.Time, to time.Time) ([]*T, error) {
-	var results []*T
+	for i, entry := range entries {
+		truncated := c.bucketStart(entry.Time)
+		path := c.timeToPath(truncated)
 
-	err := c.Find(from, to, func(t time.Time, data T) bool {
-		dataCopy := data
-		results = append(results, &dataCopy)
-		return true
-	})
+		b, ok := buckets[path]
+		if !ok {
+			b = &bucket{start: truncated}
+			b.enc = newRecordEncoder[T](&b.buf, c.Opts.Checksums)
+			buckets[path] = b
+			order = append(order, path)
+		}
 
-	return results, err
-}
+		if encoded != nil {
+			b.buf.Write(encoded[i])
+		} else if err := b.enc.Encode(entry); err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
 
-func (c *Client[T]) Find(from time.Time, to time.Time, fn func(t time.Time, data T) bool) error {
-	fromTrunc := from.Truncate(time.Hour)
-	toTrunc := to.Truncate(time.Hour).Add(time.Hour)
+		b.indexes = append(b.indexes, i)
+		b.stats.add(entry.Time)
+	}
 
-	for current := fromTrunc; current.Before(toTrunc); current = current.Add(time.Hour) {
-		path := c.timeToPath(current)
+	for _, path := range order {
+		b := buckets[path]
+		if len(b.indexes) == 0 {
+			continue
+		}
 
-		if !c.getCache(current) {
-			// Cache miss - check if file exists on disk
-			if _, err := os.Stat(path); os.IsNotExist(err) {
-				continue
+		err := ctx.Err()
+		if err == nil {
+			err = c.loadYear(b.start)
+		}
+		if err != nil {
+			for _, i := range b.indexes {
+				errs[i] = err
 			}
-			// File exists but wasn't cached, update cache
-			c.setCache(current)
+			failed = true
+			continue
 		}
 
-		shouldContinue, err := c.readFile(path, from, to, fn)
+		n, err := c.appendFile(path, b.buf.Bytes())
 		if err != nil {
-			return err
-		}
-		if !shouldContinue {
-			return nil
+			for _, i := range b.indexes {
+				errs[i] = err
+			}
+			failed = true
+			continue
 		}
+
+		b.stats.Size = n
+		c.addStats(b.start, b.stats)
+	}
+
+	if failed {
+		return &BatchError{Errs: errs}
 	}
 
 	return nil
 }
 
-func (c *Client[T]) readFile(path string, from time.Time, to time.Time, fn func(t time.Time, data T) bool) (bool, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return true, nil
+func (c *Client[T]) appendFile(path string, encoded []byte) (int64, error) {
+	if err := c.ensureManifest(false); err != nil {
+		return 0, err
+	}
+
+	if c.compression() != CompressionNone {
+		var block bytes.Buffer
+		if err := c.compressBlock(&block, encoded); err != nil {
+			return 0, err
 		}
-		return false, err
+		encoded = block.Bytes()
+	}
+
+	c.dirMu.RLock()
+	defer c.dirMu.RUnlock()
+
+	lock := c.fileLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	f, created, err := c.openAppend(path)
+	if err != nil {
+		return 0, err
 	}
 	defer f.Close()
 
-	dec := cbor.NewDecoder(f)
+	n, err := f.Write(encoded)
+	if err != nil {
+		return 0, err
+	}
+
+	if n != len(encoded) {
+		return 0, errors.New("write verification failed: bytes written != encoded length")
+	}
+
+	return int64(n), c.syncWrite(f, path, created)
+}
 
+func (c *Client[T]) openAppend(path string) (*os.File, bool, error) {
 	for {
-		var entry Entry[T]
-		err := dec.Decode(&entry)
-		if err == io.EOF {
-			break
+		if err := c.mkdirAll(filepath.Dir(path)); err != nil {
+			return nil, false, err
+		}
+		f, err := openLocked(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, true)
+		if os.IsNotExist(err) {
+			continue
 		}
 		if err != nil {
-			return false, err
+			return nil, false, err
 		}
 
-		if (entry.Time.Equal(from) || entry.Time.After(from)) &&
-			(entry.Time.Equal(to) || entry.Time.Before(to)) {
-			if !fn(entry.Time, entry.Data) {
-				return false, nil
-			}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, false, err
 		}
+		return f, fi.Size() == 0, nil
 	}
-
-	return true, nil
 }
 
-func (c *Client[T]) Delete(from time.Time, to time.Time) error {
-	fromTrunc := from.Truncate(time.Hour)
-	toTrunc := to.Truncate(time.Hour)
+func (c *Client[T]) mkdirAll(dir string) error {
+	if c.Opts.Sync == SyncNever {
+		return os.MkdirAll(dir, 0o755)
+	}
+	return mkdirAllSync(dir, c.fsync)
+}
 
-	for current := fromTrunc; current.Before(toTrunc); current = current.Add(time.Hour) {
-		if !c.getCache(current) {
-			continue
+func mkdirAllSync(dir string, fsync func(f *os.File) error) error {
+	if fi, err := os.Stat(dir); err == nil {
+		if !fi.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: dir, Err: errors.New("not a directory")}
 		}
+		return nil
+	}
 
-		path := c.timeToPath(current)
-		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+	parent := filepath.Dir(dir)
+	if parent != dir {
+		if err := mkdirAllSync(parent, fsync); err != nil {
 			return err
 		}
-		c.setCache(current)
-		c.cleanEmptyDirs(filepath.Dir(path))
 	}
 
-	return nil
-}
-
-func (c *Client[T]) cleanEmptyDirs(dir string) {
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	return syncDir(parent, fsync)
+}
+
+func (c *Client[T]) syncWrite(f *os.File, path string, created bool) error {
+	switch c.Opts.Sync {
+	case SyncNever:
+		return nil
+	case SyncAlways:
+		if err := c.fsync(f); err != nil {
+			return err
+		}
+		c.syncs.Add(1)
+		if created {
+			return syncDir(filepath.Dir(path), c.fsync)
+		}
+		return nil
+	}
+
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+
+	if c.dirty == nil {
+		c.dirty = make(map[string]bool)
+	}
+	c.dirty[path] = c.dirty[path] || created
+	c.writes++
+
+	switch c.Opts.Sync {
+	case SyncEveryN:
+		if c.writes < max(c.Opts.SyncEvery, 1) {
+			return nil
+		}
+	case SyncPeriodic:
+		if time.Since(c.lastSync) < c.Opts.SyncInterval {
+			return nil
+		}
+	}
+
+	return c.syncDirtyLocked()
+}
+
+func (c *Client[T]) syncLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.Opts.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		c.syncMu.Lock()
+		if len(c.dirty) > 0 && time.Since(c.lastSync) >= c.Opts.SyncInterval {
+			c.syncDirtyLocked()
+		}
+		c.syncMu.Unlock()
+	}
+}
+
+func (c *Client[T]) Sync() error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	return c.syncDirtyLocked()
+}
+
+func (c *Client[T]) syncDirtyLocked() error {
+	dirs := make(map[string]struct{})
+	for path, created := range c.dirty {
+		if err := syncFile(path, c.fsync); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if created {
+			dirs[filepath.Dir(path)] = struct{}{}
+		}
+		delete(c.dirty, path)
+	}
+
+	for dir := range dirs {
+		if err := syncDir(dir, c.fsync); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	c.writes = 0
+	c.lastSync = time.Now()
+	c.syncs.Add(1)
+	return nil
+}
+
+func (c *Client[T]) fsync(f *os.File) error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if c.synced != nil {
+		c.synced(f.Name())
+	}
+	return nil
+}
+
+func syncFile(path string, fsync func(f *os.File) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return fsync(f)
+}
+
+func openLocked(path string, flag int, exclusive bool) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, flag, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := lockFile(f, exclusive); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		pi, err := os.Stat(path)
+		if err == nil && os.SameFile(fi, pi) {
+			return f, nil
+		}
+		f.Close()
+
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client[T]) Get(from time.Time, to time.Time) ([]*T, error) {
+	return c.GetContext(context.Background(), from, to)
+}
+
+func (c *Client[T]) GetContext(ctx context.Context, from time.Time, to time.Time) ([]*T, error) {
+	var results []*T
+
+	err := c.FindContext(ctx, from, to, func(t time.Time, data T) bool {
+		dataCopy := data
+		results = append(results, &dataCopy)
+		return true
+	})
+
+	return results, err
+}
+
+func (c *Client[T]) GetReverse(from time.Time, to time.Time) ([]*T, error) {
+	var results []*T
+
+	err := c.FindReverse(from, to, func(t time.Time, data T) bool {
+		dataCopy := data
+		results = append(results, &dataCopy)
+		return true
+	})
+
+	return results, err
+}
+
+func (c *Client[T]) All(from time.Time, to time.Time) iter.Seq2[time.Time, T] {
+	seq, _ := c.AllErr(from, to)
+	return seq
+}
+
+func (c *Client[T]) AllErr(from time.Time, to time.Time) (iter.Seq2[time.Time, T], func() error) {
+	var err error
+	seq := func(yield func(time.Time, T) bool) {
+		err = c.Find(from, to, yield)
+	}
+	return seq, func() error { return err }
+}
+
+func (c *Client[T]) AllReverse(from time.Time, to time.Time) iter.Seq2[time.Time, T] {
+	seq, _ := c.AllReverseErr(from, to)
+	return seq
+}
+
+func (c *Client[T]) AllReverseErr(from time.Time, to time.Time) (iter.Seq2[time.Time, T], func() error) {
+	var err error
+	seq := func(yield func(time.Time, T) bool) {
+		err = c.FindReverse(from, to, yield)
+	}
+	return seq, func() error { return err }
+}
+
+func (c *Client[T]) Find(from time.Time, to time.Time, fn func(t time.Time, data T) bool) error {
+	return c.FindContext(context.Background(), from, to, fn)
+}
+
+func (c *Client[T]) FindContext(ctx context.Context, from time.Time, to time.Time, fn func(t time.Time, data T) bool) error {
+	return c.find(ctx, from, to, false, fn)
+}
+
+func (c *Client[T]) FindReverse(from time.Time, to time.Time, fn func(t time.Time, data T) bool) error {
+	return c.FindReverseContext(context.Background(), from, to, fn)
+}
+
+func (c *Client[T]) FindReverseContext(ctx context.Context, from time.Time, to time.Time, fn func(t time.Time, data T) bool) error {
+	return c.find(ctx, from, to, true, fn)
+}
+
+func (c *Client[T]) find(ctx context.Context, from time.Time, to time.Time, reverse bool, fn func(t time.Time, data T) bool) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	var buckets []time.Time
+	err := c.eachBucket(ctx, from, to, reverse, func(bucket time.Time) (bool, error) {
+		if c.skipBucket(bucket, from, to) {
+			return true, nil
+		}
+
+		if c.Opts.ReadWorkers > 1 {
+			buckets = append(buckets, bucket)
+			return true, nil
+		}
+
+		return c.readBucket(ctx, bucket, from, to, reverse, fn)
+	})
+	if err != nil || len(buckets) == 0 {
+		return err
+	}
+
+	return c.findParallel(ctx, buckets, from, to, reverse, fn)
+}
+
+func (c *Client[T]) eachBucket(ctx context.Context, from time.Time, to time.Time, reverse bool, fn func(bucket time.Time) (bool, error)) error {
+	fromTrunc, toTrunc := c.bucketRange(from, to)
+
+	if c.cacheAuthoritative() {
+		return c.eachCached(ctx, fromTrunc, toTrunc, reverse, fn)
+	}
+
+	current, next := fromTrunc, c.nextBucket
+	if reverse {
+		current, next = toTrunc, c.prevBucket
+	}
+
+	for ; !current.Before(fromTrunc) && !current.After(toTrunc); current = next(current) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !c.getCache(current) {
+			// Cache miss - check if file exists on disk
+			if !c.onDisk(current) {
+				continue
+			}
+			// File exists but wasn't cached, update cache
+			c.setCache(current)
+			c.indexChanged(current)
+		}
+
+		shouldContinue, err := fn(current)
+		if err != nil || !shouldContinue {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client[T]) readBucket(ctx context.Context, bucket time.Time, from time.Time, to time.Time, reverse bool, fn func(t time.Time, data T) bool) (bool, error) {
+	entries, readErr := c.loadBucket(ctx, bucket, from, to, reverse)
+
+	shouldContinue, err := deliverEntries(ctx, entries, fn)
+	if err != nil || !shouldContinue {
+		return false, err
+	}
+
+	return readErr == nil, readErr
+}
+
+func (c *Client[T]) loadBucket(ctx context.Context, bucket time.Time, from time.Time, to time.Time, reverse bool) ([]Entry[T], error) {
+	whole := !from.After(bucket) && !to.Before(c.bucketEnd(bucket))
+	readFrom, readTo := from, to
+	var stamp bucketStats
+	var stampErr error
+	if whole {
+		stamp, stampErr = c.bucketStamp(bucket)
+		readFrom, readTo = time.Time{}, endOfTime
+	}
+
+	var entries []Entry[T]
+	var readErr error
+	for _, path := range []string{c.sealedPath(bucket), c.timeToPath(bucket)} {
+		_, readErr = c.readFile(ctx, path, readFrom, readTo, func(t time.Time, data T) bool {
+			entries = append(entries, Entry[T]{Time: t, Data: data})
+			return true
+		})
+		if readErr != nil {
+			break
+		}
+	}
+
+	if whole {
+		if readErr == nil && stampErr == nil && stamp.Size > 0 {
+			stamp.Count = 0
+			for _, entry := range entries {
+				stamp.add(entry.Time)
+			}
+			c.learnStats(bucket, stamp)
+		}
+		entries = slices.DeleteFunc(entries, func(entry Entry[T]) bool {
+			return entry.Time.Before(from) || entry.Time.After(to)
+		})
+	}
+
+	byTime := func(a, b Entry[T]) int {
+		return a.Time.Compare(b.Time)
+	}
+	if !slices.IsSortedFunc(entries, byTime) {
+		slices.SortStableFunc(entries, byTime)
+	}
+	if reverse {
+		slices.Reverse(entries)
+	}
+
+	return entries, readErr
+}
+
+func deliverEntries[T any](ctx context.Context, entries []Entry[T], fn func(t time.Time, data T) bool) (bool, error) {
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		if !fn(entry.Time, entry.Data) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (c *Client[T]) readFile(ctx context.Context, path string, from time.Time, to time.Time, fn func(t time.Time, data T) bool) (bool, error) {
+	if strings.HasSuffix(path, sealedExt) {
+		return c.readSealed(ctx, path, from, to, fn)
+	}
+
+	buf, err := c.readLocked(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	var onCorrupt func(offset int64, err error)
+	blockCorrupt := 0
+	if c.Opts.Corruption == CorruptSkip {
+		onCorrupt = func(offset int64, err error) {
+			blockCorrupt++
+		}
+	}
+	plain, blocksValid, blockErr := c.decompress(buf, onCorrupt)
+
+	r := &recordReader[T]{buf: plain, framed: c.Opts.Checksums, skipCorrupt: c.Opts.Corruption == CorruptSkip}
+	shouldContinue := true
+	var ctxErr error
+	valid, err := decodeEntries(r, func(entry Entry[T]) bool {
+		if ctxErr = ctx.Err(); ctxErr != nil {
+			return false
+		}
+		if (entry.Time.Equal(from) || entry.Time.After(from)) &&
+			(entry.Time.Equal(to) || entry.Time.Before(to)) {
+			shouldContinue = fn(entry.Time, entry.Data)
+		}
+		return shouldContinue
+	})
+	if err == nil && ctxErr == nil && shouldContinue && blockErr != nil {
+		valid, err = blocksValid, blockErr
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		tornErr := &TornWriteError{Path: path, Offset: valid, Size: int64(len(buf))}
+		if !c.Opts.RecoverTornWrites {
+			return false, tornErr
+		}
+		if c.Opts.OnTornWrite != nil {
+			c.Opts.OnTornWrite(tornErr)
+		}
+		err = nil
+	}
+	c.corrupt.Add(int64(r.corrupt + blockCorrupt))
+	if corruptErr, ok := err.(*CorruptRecordError); ok {
+		corruptErr.Path = path
+	}
+	if err != nil {
+		return false, err
+	}
+	if ctxErr != nil {
+		return false, ctxErr
+	}
+
+	return shouldContinue, nil
+}
+
+func (c *Client[T]) CorruptRecords() int64 {
+	return c.corrupt.Load()
+}
+
+func (c *Client[T]) Repair(bucket time.Time) (int64, error) {
+	if err := c.writable(); err != nil {
+		return 0, err
+	}
+
+	path := c.timeToPath(c.bucketStart(bucket))
+
+	lock := c.fileLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	f, err := openLocked(path, os.O_RDWR, true)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		return 0, err
+	}
+
+	var valid int64
+	if c.compression() != CompressionNone {
+		_, valid, err = c.decompress(buf, func(offset int64, err error) {})
+	} else {
+		r := &recordReader[T]{buf: buf, framed: c.Opts.Checksums, skipCorrupt: true}
+		valid, err = decodeEntries(r, func(entry Entry[T]) bool {
+			return true
+		})
+	}
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		if corruptErr, ok := err.(*CorruptRecordError); ok {
+			corruptErr.Path = path
+		}
+		return 0, err
+	}
+	if c.compression() != CompressionNone && c.codec().nextBlock(buf, int(valid)+1) >= 0 {
+		return 0, &CorruptRecordError{Path: path, Offset: valid, Err: ErrInvalidBlock}
+	}
+	if c.compression() == CompressionNone && c.Opts.Checksums && nextFrame(buf, int(valid)+1) >= 0 {
+		return 0, &CorruptRecordError{Path: path, Offset: valid, Err: ErrInvalidFrame}
+	}
+
+	if err := f.Truncate(valid); err != nil {
+		return 0, err
+	}
+
+	if c.Opts.Sync != SyncNever {
+		if err := c.fsync(f); err != nil {
+			return 0, err
+		}
+	}
+
+	size, err := c.bucketSize(c.bucketStart(bucket))
+	if err != nil {
+		return 0, err
+	}
+	c.setStats(c.bucketStart(bucket), bucketStats{Size: size, Count: -1})
+
+	return int64(len(buf)) - valid, nil
+}
+
+func (c *Client[T]) readLocked(path string) ([]byte, error) {
+	lock := c.fileLock(path)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	f, err := openLocked(path, os.O_RDONLY, false)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+func (c *Client[T]) Delete(from time.Time, to time.Time) error {
+	return c.DeleteContext(context.Background(), from, to)
+}
+
+func (c *Client[T]) DeleteContext(ctx context.Context, from time.Time, to time.Time) error {
+	if err := c.writable(); err != nil {
+		return err
+	}
+
+	fromTrunc, toTrunc := c.bucketRange(from, to)
+
+	for current := fromTrunc; !current.After(toTrunc); current = c.nextBucket(current) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		path := c.timeToPath(current)
+
+		if !c.getCache(current) && !c.onDisk(current) {
+			continue
+		}
+
+		if !current.Before(from) && !c.bucketEnd(current).After(to) {
+			sealed := c.sealedPath(current)
+			lock := c.fileLock(path)
+			lock.Lock()
+			sealedLock := c.fileLock(sealed)
+			sealedLock.Lock()
+			err := removeLocked(path)
+			if err == nil {
+				err = removeLocked(sealed)
+			}
+			sealedLock.Unlock()
+			lock.Unlock()
+			if err != nil {
+				return err
+			}
+			c.clearCache(current)
+			c.cleanEmptyDirs(filepath.Dir(path))
+			continue
+		}
+
+		_, err := c.rewriteFile(current, func(entry Entry[T]) bool {
+			return entry.Time.Before(from) || entry.Time.After(to)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client[T]) DeleteWhere(from time.Time, to time.Time, fn func(t time.Time, data T) bool) (map[time.Time]int, error) {
+	return c.DeleteWhereContext(context.Background(), from, to, fn)
+}
+
+func (c *Client[T]) DeleteWhereContext(ctx context.Context, from time.Time, to time.Time, fn func(t time.Time, data T) bool) (map[time.Time]int, error) {
+	if err := c.writable(); err != nil {
+		return nil, err
+	}
+
+	removed := make(map[time.Time]int)
+	fromTrunc, toTrunc := c.bucketRange(from, to)
+
+	for current := fromTrunc; !current.After(toTrunc); current = c.nextBucket(current) {
+		if err := ctx.Err(); err != nil {
+			return removed, err
+		}
+
+		if !c.getCache(current) && !c.onDisk(current) {
+			continue
+		}
+
+		n, err := c.rewriteFile(current, func(entry Entry[T]) bool {
+			if entry.Time.Before(from) || entry.Time.After(to) {
+				return true
+			}
+			return !fn(entry.Time, entry.Data)
+		})
+		if err != nil {
+			return removed, err
+		}
+		if n > 0 {
+			removed[current] = n
+		}
+	}
+
+	return removed, nil
+}
+
+func (c *Client[T]) rewriteFile(bucket time.Time, keep func(entry Entry[T]) bool) (int, error) {
+	path := c.timeToPath(bucket)
+	sealed := c.sealedPath(bucket)
+
+	var kept bucketStats
+	track := func(entry Entry[T]) bool {
+		if !keep(entry) {
+			return false
+		}
+		kept.add(entry.Time)
+		return true
+	}
+
+	c.dirMu.RLock()
+	lock := c.fileLock(path)
+	lock.Lock()
+	sealedLock := c.fileLock(sealed)
+	sealedLock.Lock()
+	removed, remaining, size, err := c.rewriteLocked(path, track)
+	if err == nil {
+		var sealedRemoved, sealedRemaining int
+		var sealedSize int64
+		sealedRemoved, sealedRemaining, sealedSize, err = c.rewriteSealedLocked(sealed, track)
+		removed += sealedRemoved
+		remaining += sealedRemaining
+		size += sealedSize
+	}
+	var stamp bucketStats
+	if err == nil {
+		stamp, err = c.bucketStamp(bucket)
+	}
+	sealedLock.Unlock()
+	lock.Unlock()
+	c.dirMu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	if remaining == 0 && removed > 0 {
+		c.clearCache(bucket)
+		c.cleanEmptyDirs(filepath.Dir(path))
+	}
+
+	if remaining > 0 {
+		kept.Size = stamp.Size
+		if stamp.Size == size {
+			kept.Files = stamp.Files
+		}
+		c.setStats(bucket, kept)
+	}
+
+	return removed, nil
+}
+
+func (c *Client[T]) rewriteLocked(path string, keep func(entry Entry[T]) bool) (int, int, int64, error) {
+	f, err := openLocked(path, os.O_RDWR, true)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, 0, nil
+		}
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var onCorrupt func(offset int64, err error)
+	if c.Opts.Corruption == CorruptSkip {
+		onCorrupt = func(offset int64, err error) {}
+	}
+	plain, _, err := c.decompress(buf, onCorrupt)
+	if errors.Is(err, io.ErrUnexpectedEOF) && c.Opts.RecoverTornWrites {
+		err = nil
+	}
+	if err != nil {
+		if corruptErr, ok := err.(*CorruptRecordError); ok {
+			corruptErr.Path = path
+		}
+		return 0, 0, 0, err
+	}
+
+	var kept bytes.Buffer
+	removed, remaining := 0, 0
+	r := &recordReader[T]{buf: plain, framed: c.Opts.Checksums, skipCorrupt: c.Opts.Corruption == CorruptSkip}
+	for {
+		start := r.off
+		entry, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) && c.Opts.RecoverTornWrites {
+			break
+		}
+		if err != nil {
+			if corruptErr, ok := err.(*CorruptRecordError); ok {
+				corruptErr.Path = path
+			}
+			return 0, 0, 0, err
+		}
+
+		if keep(entry) {
+			kept.Write(plain[start:r.off])
+			remaining++
+		} else {
+			removed++
+		}
+	}
+
+	if removed == 0 {
+		return 0, remaining, int64(len(buf)), nil
+	}
+
+	if remaining == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return 0, 0, 0, err
+		}
+		return removed, 0, 0, nil
+	}
+
+	var out bytes.Buffer
+	if err := c.compressBlock(&out, kept.Bytes()); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := c.replaceFile(path, out.Bytes()); err != nil {
+		return 0, 0, 0, err
+	}
+
+	return removed, remaining, int64(out.Len()), nil
+}
+
+func (c *Client[T]) replaceFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if c.Opts.Sync != SyncNever {
+		if err := c.fsync(tmp); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	if c.Opts.Sync != SyncNever {
+		return syncDir(dir, c.fsync)
+	}
+
+	return nil
+}
+
+func removeLocked(path string) error {
+	f, err := openLocked(path, os.O_RDONLY, true)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Client[T]) cleanEmptyDirs(dir string) {
+	c.dirMu.Lock()
+	defer c.dirMu.Unlock()
 	for dir != c.Opts.Path && dir != "" {
 		entries, err := os.ReadDir(dir)
 		if err != nil || len(entries) > 0 {
@@ -291,12 +1531,22 @@ func (c *Client[T]) cleanEmptyDirs(dir string) {
 	}
 }
 
-func initDebugPrintLoop(opts Options) {
+func (c *Client[T]) debugPrintLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
 	for {
-		if opts.PrintMemory {
+		if c.Opts.PrintMemory {
 			printMemUsage()
 		}
-		time.Sleep(1 * time.Second)
+
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
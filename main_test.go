@@ -1,8 +1,16 @@
 package timeseries
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -190,7 +198,7 @@ func Test_Delete(t *testing.T) {
 	}
 
 	// Delete the first 2 hours (10:00 and 11:00)
-	err = c.Delete(baseTime, baseTime.Add(2*time.Hour))
+	err = c.Delete(baseTime, baseTime.Add(2*time.Hour-time.Second))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -984,7 +992,7 @@ func Test_FindUncachedDataOnDisk(t *testing.T) {
 
 	// Create a new client WITHOUT building the cache (empty cache)
 	c2 := &Client[testStruct]{
-		Cache: make(map[int]*[12][31][24]struct{}),
+		Cache: make(map[int][]uint64),
 		Opts:  Options{Path: dir},
 	}
 
@@ -1012,3 +1020,1565 @@ func Test_FindUncachedDataOnDisk(t *testing.T) {
 		t.Fatal("expected cache to be populated after Find")
 	}
 }
+
+func Test_ConcurrentStoreSameHour(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	workers := 8
+	perWorker := 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				err := c.Store(baseTime.Add(time.Duration(w*perWorker+i)*time.Second), testStruct{
+					SomeString: strings.Repeat("x", 512),
+					SomeInt:    w*perWorker + i,
+				})
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(59*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != workers*perWorker {
+		t.Fatalf("expected %d results, got %d", workers*perWorker, len(results))
+	}
+
+	seen := make(map[int]bool)
+	for _, r := range results {
+		if len(r.SomeString) != 512 {
+			t.Fatalf("corrupted record for %d", r.SomeInt)
+		}
+		seen[r.SomeInt] = true
+	}
+
+	if len(seen) != workers*perWorker {
+		t.Fatalf("expected %d unique records, got %d", workers*perWorker, len(seen))
+	}
+}
+
+func Test_ConcurrentStoreFindDelete(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for w := 0; w < 4; w++ {
+		wg.Add(3)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				err := c.Store(baseTime.Add(time.Duration(i%6)*time.Hour+time.Duration(w)*time.Minute), testStruct{SomeInt: i})
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				err := c.Find(baseTime, baseTime.Add(6*time.Hour), func(tm time.Time, data testStruct) bool {
+					return true
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				from := baseTime.Add(time.Duration((w+i)%6) * time.Hour)
+				if err := c.Delete(from, from.Add(time.Hour)); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get(baseTime, baseTime.Add(6*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_MultipleClientsSameDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c1, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i, c := range []*Client[testStruct]{c1, c2} {
+		wg.Add(1)
+		go func(i int, c *Client[testStruct]) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				err := c.Store(baseTime.Add(time.Duration(j)*time.Second), testStruct{
+					SomeString: strings.Repeat("y", 256),
+					SomeInt:    i*100 + j,
+				})
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	results, err := c1.Get(baseTime, baseTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 200 {
+		t.Fatalf("expected 200 results, got %d", len(results))
+	}
+}
+
+func Test_Refresh(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	reader, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	if err := writer.Store(baseTime, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Store(baseTime.Add(24*time.Hour), testStruct{SomeInt: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if reader.getCache(baseTime.Truncate(time.Hour)) {
+		t.Fatal("expected reader cache to be empty")
+	}
+
+	if err := reader.Refresh(baseTime, baseTime.Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reader.getCache(baseTime.Truncate(time.Hour)) {
+		t.Fatal("expected reader cache to contain first hour after refresh")
+	}
+
+	if !reader.getCache(baseTime.Add(24 * time.Hour).Truncate(time.Hour)) {
+		t.Fatal("expected reader cache to contain second day after refresh")
+	}
+}
+
+func Test_StoreBatch(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	var entries []Entry[testStruct]
+	for i := 0; i < 300; i++ {
+		entries = append(entries, Entry[testStruct]{
+			Time: baseTime.Add(time.Duration(i) * time.Minute),
+			Data: testStruct{SomeString: "batch", SomeInt: i},
+		})
+	}
+
+	if err := c.StoreBatch(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	for h := 0; h < 5; h++ {
+		path := filepath.Join(tmpDir, "2024", "06", "15", fmt.Sprintf("%02d.cbor", 10+h))
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected file at %s", path)
+		}
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(300*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 300 {
+		t.Fatalf("expected 300 results, got %d", len(results))
+	}
+
+	for i, r := range results {
+		if r.SomeInt != i {
+			t.Fatalf("expected SomeInt=%d at index %d, got %d", i, i, r.SomeInt)
+		}
+	}
+}
+
+func Test_StoreBatchPerEntryErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[any](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	entries := []Entry[any]{
+		{Time: baseTime, Data: 1},
+		{Time: baseTime.Add(time.Minute), Data: make(chan int)},
+		{Time: baseTime.Add(2 * time.Minute), Data: 3},
+	}
+
+	err = c.StoreBatch(entries)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("expected BatchError, got %v", err)
+	}
+
+	if len(batchErr.Errs) != 3 {
+		t.Fatalf("expected 3 per-entry errors, got %d", len(batchErr.Errs))
+	}
+
+	if batchErr.Errs[0] != nil || batchErr.Errs[1] == nil || batchErr.Errs[2] != nil {
+		t.Fatalf("unexpected per-entry errors: %v", batchErr.Errs)
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+}
+
+func Test_StoreBatchEmpty(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.StoreBatch(nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func storeAndRecordSizes(t *testing.T, c *Client[testStruct], base time.Time, n int) []int64 {
+	t.Helper()
+
+	path := c.timeToPath(base)
+	sizes := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		if err := c.Store(base.Add(time.Duration(i)*time.Second), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, fi.Size())
+	}
+	return sizes
+}
+
+func simulateCrash(t *testing.T, path string, size int64) {
+	t.Helper()
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func recordSyncs(c *Client[testStruct]) func(path string) int {
+	var mu sync.Mutex
+	synced := make(map[string]int)
+	c.synced = func(path string) {
+		mu.Lock()
+		synced[path]++
+		mu.Unlock()
+	}
+	return func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return synced[path]
+	}
+}
+
+func Test_SyncNever(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, baseTime, 5)
+
+	if c.syncs.Load() != 0 {
+		t.Fatalf("expected no syncs, got %d", c.syncs.Load())
+	}
+
+	// Without fsync a crash may lose every write, the file can come back empty
+	simulateCrash(t, c.timeToPath(baseTime), 0)
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected 0 results, got %d", len(results))
+	}
+}
+
+func Test_SyncAlways(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Sync: SyncAlways})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	synced := recordSyncs(c)
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	sizes := storeAndRecordSizes(t, c, baseTime, 5)
+
+	if c.syncs.Load() != 5 {
+		t.Fatalf("expected 5 syncs, got %d", c.syncs.Load())
+	}
+	path := c.timeToPath(baseTime)
+	if n := synced(path); n != 5 {
+		t.Fatalf("expected the hour file to be fsynced 5 times, got %d", n)
+	}
+	for _, dir := range []string{filepath.Dir(path), filepath.Join(tmpDir, "2024")} {
+		if synced(dir) == 0 {
+			t.Fatalf("expected %s to be fsynced after creating entries in it", dir)
+		}
+	}
+
+	// Every acknowledged Store is durable, a crash after write N keeps N entries
+	for n := len(sizes); n > 0; n-- {
+		simulateCrash(t, c.timeToPath(baseTime), sizes[n-1])
+
+		results, err := c.Get(baseTime, baseTime.Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != n {
+			t.Fatalf("expected %d results after crash, got %d", n, len(results))
+		}
+	}
+}
+
+func Test_SyncEveryN(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Sync: SyncEveryN, SyncEvery: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	synced := recordSyncs(c)
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	sizes := storeAndRecordSizes(t, c, baseTime, 7)
+
+	if c.syncs.Load() != 2 {
+		t.Fatalf("expected 2 syncs, got %d", c.syncs.Load())
+	}
+	path := c.timeToPath(baseTime)
+	if n := synced(path); n != 2 {
+		t.Fatalf("expected the hour file to be fsynced 2 times, got %d", n)
+	}
+
+	// Only the first 6 writes were synced, a crash may drop the 7th
+	simulateCrash(t, c.timeToPath(baseTime), sizes[5])
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("expected 6 results after crash, got %d", len(results))
+	}
+
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if c.syncs.Load() != 3 {
+		t.Fatalf("expected explicit Sync to flush, got %d syncs", c.syncs.Load())
+	}
+	if n := synced(path); n != 3 {
+		t.Fatalf("expected explicit Sync to fsync the hour file, got %d fsyncs", n)
+	}
+}
+
+func Test_SyncPeriodic(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Sync: SyncPeriodic, SyncInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	synced := recordSyncs(c)
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	sizes := storeAndRecordSizes(t, c, baseTime, 4)
+
+	// The first write syncs immediately, the rest wait for the interval
+	if c.syncs.Load() != 1 {
+		t.Fatalf("expected 1 sync, got %d", c.syncs.Load())
+	}
+	if n := synced(c.timeToPath(baseTime)); n != 1 {
+		t.Fatalf("expected the hour file to be fsynced once, got %d", n)
+	}
+
+	simulateCrash(t, c.timeToPath(baseTime), sizes[0])
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result after crash, got %d", len(results))
+	}
+}
+
+func Test_SyncPeriodicTimer(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Sync: SyncPeriodic, SyncInterval: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	synced := recordSyncs(c)
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, baseTime, 3)
+	path := c.timeToPath(baseTime)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for synced(path) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the timer to fsync the hour file without further writes, got %d fsyncs", synced(path))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func Test_SyncCreatesDirectories(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: filepath.Join(tmpDir, "nested", "store"), Sync: SyncAlways})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := c.Store(baseTime, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(c.timeToPath(baseTime)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tearLastRecord(t *testing.T, path string) int64 {
+	t.Helper()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	simulateCrash(t, path, fi.Size()-3)
+	return fi.Size() - 3
+}
+
+func Test_TornWriteFailsByDefault(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, baseTime, 3)
+	tearLastRecord(t, c.timeToPath(baseTime))
+
+	_, err = c.Get(baseTime, baseTime.Add(time.Minute))
+	var tornErr *TornWriteError
+	if !errors.As(err, &tornErr) {
+		t.Fatalf("expected TornWriteError, got %v", err)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal("expected TornWriteError to wrap io.ErrUnexpectedEOF")
+	}
+}
+
+func Test_TornWriteRecovery(t *testing.T) {
+	var reported []*TornWriteError
+	c, err := Init[testStruct](Options{
+		Path:              t.TempDir(),
+		RecoverTornWrites: true,
+		OnTornWrite: func(err *TornWriteError) {
+			reported = append(reported, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	sizes := storeAndRecordSizes(t, c, baseTime, 3)
+	if err := c.Store(baseTime.Add(time.Hour), testStruct{SomeInt: 99}); err != nil {
+		t.Fatal(err)
+	}
+	size := tearLastRecord(t, c.timeToPath(baseTime))
+
+	results, err := c.Get(baseTime, baseTime.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	if results[2].SomeInt != 99 {
+		t.Fatal("expected the next hour to be read after a torn record")
+	}
+
+	if len(reported) != 1 {
+		t.Fatalf("expected 1 torn write report, got %d", len(reported))
+	}
+
+	if reported[0].Offset != sizes[1] || reported[0].Size != size {
+		t.Fatalf("unexpected torn write report: %+v", reported[0])
+	}
+}
+
+func Test_Repair(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	sizes := storeAndRecordSizes(t, c, baseTime, 3)
+	size := tearLastRecord(t, c.timeToPath(baseTime))
+
+	removed, err := c.Repair(baseTime.Add(30 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if removed != size-sizes[1] {
+		t.Fatalf("expected %d bytes removed, got %d", size-sizes[1], removed)
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results after repair, got %d", len(results))
+	}
+
+	removed, err = c.Repair(baseTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 0 {
+		t.Fatalf("expected healthy file to be left alone, removed %d bytes", removed)
+	}
+
+	if err := c.Store(baseTime.Add(time.Minute), testStruct{SomeInt: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = c.Get(baseTime, baseTime.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results after appending to repaired file, got %d", len(results))
+	}
+}
+
+func Test_RepairKeepsCorruptFile(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	sizes := storeAndRecordSizes(t, c, baseTime, 3)
+	path := c.timeToPath(baseTime)
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[sizes[0]] = 0x1c
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := c.Repair(baseTime)
+	var corruptErr *CorruptRecordError
+	if !errors.As(err, &corruptErr) {
+		t.Fatalf("expected CorruptRecordError, got %v", err)
+	}
+	if corruptErr.Path != path || corruptErr.Offset != sizes[0] {
+		t.Fatalf("unexpected corrupt record location: %+v", corruptErr)
+	}
+	if removed != 0 {
+		t.Fatalf("expected nothing removed, got %d", removed)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != sizes[2] {
+		t.Fatalf("expected the corrupt file to keep %d bytes, got %d", sizes[2], fi.Size())
+	}
+}
+
+func Test_RepairKeepsFramesAfterDamage(t *testing.T) {
+	for _, compression := range []string{CompressionNone, CompressionZstd} {
+		c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true, Compression: compression, RecoverTornWrites: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+		sizes := storeAndRecordSizes(t, c, baseTime, 5)
+		path := c.timeToPath(baseTime)
+
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf[sizes[0]] = 0x7b
+		if err := os.WriteFile(path, buf, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := c.Repair(baseTime); err != nil {
+			t.Fatalf("%s: %v", compression, err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != sizes[4] {
+			t.Fatalf("%s: expected repair to keep all %d bytes, got %d", compression, sizes[4], fi.Size())
+		}
+	}
+}
+
+func Test_All(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	for i := 0; i < 90; i++ {
+		if err := c.Store(baseTime.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	i := 0
+	for tm, data := range c.All(baseTime, baseTime.Add(89*time.Minute)) {
+		if !tm.Equal(baseTime.Add(time.Duration(i) * time.Minute)) {
+			t.Fatalf("unexpected time %s at index %d", tm, i)
+		}
+		if data.SomeInt != i {
+			t.Fatalf("expected SomeInt=%d, got %d", i, data.SomeInt)
+		}
+		i++
+	}
+
+	if i != 90 {
+		t.Fatalf("expected 90 items, got %d", i)
+	}
+
+	collected := maps.Collect(c.All(baseTime, baseTime.Add(9*time.Minute)))
+	if len(collected) != 10 {
+		t.Fatalf("expected 10 collected items, got %d", len(collected))
+	}
+}
+
+func Test_AllEarlyBreak(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := c.Store(baseTime.Add(time.Duration(i)*time.Hour), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count := 0
+	for _, data := range c.All(baseTime, baseTime.Add(3*time.Hour)) {
+		count++
+		if data.SomeInt == 1 {
+			break
+		}
+	}
+
+	if count != 2 {
+		t.Fatalf("expected 2 items before break, got %d", count)
+	}
+
+	if err := c.Store(baseTime.Add(time.Hour), testStruct{SomeInt: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Delete(baseTime, baseTime.Add(3*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_AllErr(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, baseTime, 3)
+	tearLastRecord(t, c.timeToPath(baseTime))
+
+	seq, errFn := c.AllErr(baseTime, baseTime.Add(time.Hour))
+	values := slices.Collect(func(yield func(int) bool) {
+		for _, data := range seq {
+			if !yield(data.SomeInt) {
+				return
+			}
+		}
+	})
+
+	if len(values) != 2 {
+		t.Fatalf("expected 2 values before the torn record, got %d", len(values))
+	}
+
+	var tornErr *TornWriteError
+	if !errors.As(errFn(), &tornErr) {
+		t.Fatalf("expected TornWriteError, got %v", errFn())
+	}
+}
+
+func Test_AllReverseErr(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, baseTime.Add(time.Hour), 2)
+	storeAndRecordSizes(t, c, baseTime, 3)
+	tearLastRecord(t, c.timeToPath(baseTime))
+
+	seq, errFn := c.AllReverseErr(baseTime, baseTime.Add(2*time.Hour))
+	var values []int
+	for _, data := range seq {
+		values = append(values, data.SomeInt)
+	}
+
+	if !slices.Equal(values, []int{1, 0, 1, 0}) {
+		t.Fatalf("expected the records before the torn one latest first, got %v", values)
+	}
+
+	var tornErr *TornWriteError
+	if !errors.As(errFn(), &tornErr) {
+		t.Fatalf("expected TornWriteError, got %v", errFn())
+	}
+}
+
+func Test_FindContextCancelledBetweenRecords(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		if err := c.Store(baseTime.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	count := 0
+	err = c.FindContext(ctx, baseTime, baseTime.Add(time.Hour), func(tm time.Time, data testStruct) bool {
+		count++
+		if count == 3 {
+			cancel()
+		}
+		return true
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 callbacks before cancellation, got %d", count)
+	}
+}
+
+func Test_GetContextCancelledBetweenHours(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := c.Store(baseTime, testStruct{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := c.GetContext(ctx, baseTime, baseTime.Add(24*time.Hour))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected no results, got %d", len(results))
+	}
+}
+
+func Test_StoreContextCancelled(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := c.StoreContext(ctx, baseTime, testStruct{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	err = c.StoreBatchContext(ctx, []Entry[testStruct]{{Time: baseTime}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled from batch, got %v", err)
+	}
+
+	if _, err := os.Stat(c.timeToPath(baseTime)); !os.IsNotExist(err) {
+		t.Fatal("expected no file to be written")
+	}
+}
+
+func Test_DeleteContextCancelled(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := c.Store(baseTime, testStruct{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := c.DeleteContext(ctx, baseTime, baseTime.Add(2*time.Hour)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if _, err := os.Stat(c.timeToPath(baseTime)); err != nil {
+		t.Fatal("expected file to survive a cancelled delete")
+	}
+}
+
+func Test_DeleteSubHour(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 60; i++ {
+		if err := c.Store(baseTime.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = c.Delete(baseTime.Add(15*time.Minute), baseTime.Add(45*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 29 {
+		t.Fatalf("expected 29 results, got %d", len(results))
+	}
+
+	for _, r := range results {
+		if r.SomeInt >= 15 && r.SomeInt <= 45 {
+			t.Fatalf("entry %d should have been deleted", r.SomeInt)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(c.timeToPath(baseTime)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the rewritten hour file, got %d entries", len(entries))
+	}
+}
+
+func Test_DeleteAcrossPartialHours(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 4*60; i += 10 {
+		if err := c.Store(baseTime.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	from := baseTime.Add(30 * time.Minute)
+	to := baseTime.Add(2*time.Hour + 20*time.Minute)
+	if err := c.Delete(from, to); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(c.timeToPath(baseTime.Add(time.Hour))); !os.IsNotExist(err) {
+		t.Fatal("expected fully covered hour to be removed")
+	}
+
+	if c.getCache(baseTime.Add(time.Hour)) {
+		t.Fatal("expected cache to be cleared for the removed hour")
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(4*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var remaining []int
+	for _, r := range results {
+		remaining = append(remaining, r.SomeInt)
+	}
+
+	expected := []int{0, 10, 20, 150, 160, 170, 180, 190, 200, 210, 220, 230}
+	if !slices.Equal(remaining, expected) {
+		t.Fatalf("expected %v, got %v", expected, remaining)
+	}
+}
+
+func Test_DeleteIncludesToHour(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := c.Store(baseTime.Add(time.Duration(i)*time.Hour), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Delete(baseTime.Add(2*time.Hour), baseTime.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	if _, err := os.Stat(c.timeToPath(baseTime.Add(2 * time.Hour))); !os.IsNotExist(err) {
+		t.Fatal("expected emptied hour file to be removed")
+	}
+
+	if c.getCache(baseTime.Add(2 * time.Hour)) {
+		t.Fatal("expected cache to be cleared for the emptied hour")
+	}
+}
+
+func Test_DeleteSubHourKeepsFraming(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, baseTime, 5)
+
+	if err := c.Delete(baseTime.Add(time.Second), baseTime.Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := os.ReadFile(c.timeToPath(baseTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != frameMarker {
+		t.Fatal("expected rewritten file to keep framed records")
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+}
+
+func Test_DeleteWhere(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3*60; i += 5 {
+		sensor := "good"
+		if i%15 == 0 {
+			sensor = "bad"
+		}
+		if err := c.Store(baseTime.Add(time.Duration(i)*time.Minute), testStruct{SomeString: sensor, SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := c.DeleteWhere(baseTime, baseTime.Add(time.Hour+59*time.Minute), func(tm time.Time, data testStruct) bool {
+		return data.SomeString == "bad"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[time.Time]int{
+		baseTime:                4,
+		baseTime.Add(time.Hour): 4,
+	}
+	if !maps.Equal(removed, expected) {
+		t.Fatalf("expected %v removed, got %v", expected, removed)
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bad := 0
+	for _, r := range results {
+		if r.SomeString == "bad" {
+			bad++
+			if r.SomeInt < 120 {
+				t.Fatalf("entry %d should have been deleted", r.SomeInt)
+			}
+		}
+	}
+
+	if len(results) != 36-8 || bad != 4 {
+		t.Fatalf("expected 28 results with 4 bad outside the range, got %d with %d bad", len(results), bad)
+	}
+}
+
+func Test_DeleteWhereRemovesEmptiedFile(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, baseTime, 3)
+
+	removed, err := c.DeleteWhere(baseTime, baseTime.Add(time.Hour), func(tm time.Time, data testStruct) bool {
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if removed[baseTime] != 3 {
+		t.Fatalf("expected 3 removed, got %v", removed)
+	}
+
+	if _, err := os.Stat(filepath.Join(c.Opts.Path, "2024")); !os.IsNotExist(err) {
+		t.Fatal("expected empty directories to be cleaned up")
+	}
+
+	if c.getCache(baseTime) {
+		t.Fatal("expected cache to be cleared")
+	}
+}
+
+func Test_BucketLayouts(t *testing.T) {
+	when := time.Date(2024, 6, 15, 10, 37, 12, 0, time.UTC)
+
+	cases := []struct {
+		bucket time.Duration
+		path   []string
+	}{
+		{time.Minute, []string{"2024", "06", "15", "10", "37.cbor"}},
+		{15 * time.Minute, []string{"2024", "06", "15", "10", "30.cbor"}},
+		{90 * time.Minute, []string{"2024", "06", "15", "10", "30.cbor"}},
+		{time.Hour, []string{"2024", "06", "15", "10.cbor"}},
+		{6 * time.Hour, []string{"2024", "06", "15", "06.cbor"}},
+		{24 * time.Hour, []string{"2024", "06", "15.cbor"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.bucket.String(), func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			c, err := Init[testStruct](Options{Path: tmpDir, Bucket: tc.bucket})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.Store(when, testStruct{SomeInt: 1}); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := filepath.Join(append([]string{tmpDir}, tc.path...)...)
+			if _, err := os.Stat(expected); err != nil {
+				t.Fatalf("expected file at %s", expected)
+			}
+
+			bucket, err := c.parsePathToTime(expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bucket.Equal(when.Truncate(tc.bucket)) {
+				t.Fatalf("expected bucket %s, got %s", when.Truncate(tc.bucket), bucket)
+			}
+
+			reopened, err := Init[testStruct](Options{Path: tmpDir, Bucket: tc.bucket})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reopened.getCache(when.Truncate(tc.bucket)) {
+				t.Fatal("expected bucket to be cached on init")
+			}
+
+			results, err := reopened.Get(when, when)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("expected 1 result, got %d", len(results))
+			}
+		})
+	}
+}
+
+func Test_InvalidBucket(t *testing.T) {
+	for _, bucket := range []time.Duration{time.Second, 7 * time.Minute, 90 * time.Second, 48 * time.Hour, -time.Hour} {
+		_, err := Init[testStruct](Options{Path: t.TempDir(), Bucket: bucket})
+		if !errors.Is(err, ErrInvalidBucket) {
+			t.Fatalf("expected ErrInvalidBucket for %s, got %v", bucket, err)
+		}
+	}
+}
+
+func Test_MinuteBucketFindAndDelete(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Bucket: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 58, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		if err := c.Store(baseTime.Add(time.Duration(i)*30*time.Second), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := c.Get(baseTime.Add(time.Minute), baseTime.Add(3*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+
+	if err := c.Delete(baseTime.Add(time.Minute), baseTime.Add(2*time.Minute+30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "2024", "06", "15", "10", "59.cbor")); !os.IsNotExist(err) {
+		t.Fatal("expected fully covered minute file to be removed")
+	}
+
+	results, err = c.Get(baseTime, baseTime.Add(5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("expected 6 results after delete, got %d", len(results))
+	}
+}
+
+func Test_StoreNonUTCTimeUsesUTCPath(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zone := time.FixedZone("UTC+5:30", 5*3600+1800)
+	when := time.Date(2024, 6, 15, 16, 15, 0, 0, zone)
+	if err := c.Store(when, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "2024", "06", "15", "10.cbor")); err != nil {
+		t.Fatalf("expected entry in the UTC hour file: %v", err)
+	}
+
+	reopened, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := reopened.Get(when.UTC().Add(-time.Minute), when.UTC().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	report, err := reopened.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("expected no issues, got %+v", report.Issues)
+	}
+}
+
+func Test_LocationHalfHourOffset(t *testing.T) {
+	tmpDir := t.TempDir()
+	zone := time.FixedZone("UTC+5:30", 5*3600+1800)
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Location: zone})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2024, 6, 15, 10, 45, 0, 0, time.UTC)
+	if err := c.Store(when, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "2024", "06", "15", "16.cbor")); err != nil {
+		t.Fatalf("expected entry in the local hour file: %v", err)
+	}
+
+	bucket := c.bucketStart(when)
+	if !bucket.Equal(time.Date(2024, 6, 15, 16, 0, 0, 0, zone)) {
+		t.Fatalf("expected bucket to start on the local hour, got %s", bucket)
+	}
+
+	results, err := c.Get(bucket, bucket.Add(time.Hour-time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	if _, err := Init[testStruct](Options{Path: tmpDir, Location: zone}); err != nil {
+		t.Fatalf("expected store to be reopened with its own location, got %v", err)
+	}
+
+	_, err = Init[testStruct](Options{Path: tmpDir, Location: time.UTC})
+	if !errors.Is(err, ErrManifestMismatch) {
+		t.Fatalf("expected ErrManifestMismatch, got %v", err)
+	}
+}
+
+func Test_LocationDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tmpDir := t.TempDir()
+	c, err := Init[testStruct](Options{Path: tmpDir, Location: loc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2024, 11, 3, 0, 0, 0, 0, loc)
+	to := time.Date(2024, 11, 3, 4, 0, 0, 0, loc)
+	n := 0
+	for when := from; when.Before(to); when = when.Add(30 * time.Minute) {
+		if err := c.Store(when, testStruct{SomeInt: n}); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+
+	results, err := c.Get(from, to.Add(-time.Nanosecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != n {
+		t.Fatalf("expected %d results across the DST change, got %d", n, len(results))
+	}
+
+	if err := c.Delete(from, to.Add(-time.Nanosecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = c.Get(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected no results after delete, got %d", len(results))
+	}
+}
+
+func Test_LocationDaylightSavingSubHour(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Location: loc, Bucket: 30 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 11, 3, 4, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 11, 3, 8, 0, 0, 0, time.UTC)
+	n := 0
+	for when := start; when.Before(end); when = when.Add(10 * time.Minute) {
+		if err := c.Store(when, testStruct{SomeInt: n}); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+
+	edt := time.Date(2024, 11, 3, 5, 40, 0, 0, time.UTC)
+	est := time.Date(2024, 11, 3, 6, 10, 0, 0, time.UTC)
+	if edt.In(loc).Hour() != 1 || est.In(loc).Hour() != 1 {
+		t.Fatalf("expected both ends in the repeated hour, got %s and %s", edt.In(loc), est.In(loc))
+	}
+
+	for _, reverse := range []bool{false, true} {
+		var found []int
+		find := c.Find
+		if reverse {
+			find = c.FindReverse
+		}
+		err := find(edt, est, func(_ time.Time, data testStruct) bool {
+			found = append(found, data.SomeInt)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(found)
+		if !slices.Equal(found, []int{10, 11, 12, 13}) {
+			t.Fatalf("expected the 4 entries between 01:40 EDT and 01:10 EST, got %v", found)
+		}
+	}
+
+	if err := c.Delete(edt, est); err != nil {
+		t.Fatal(err)
+	}
+	results, err := c.Get(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != n-4 {
+		t.Fatalf("expected %d entries after the delete, got %d", n-4, len(results))
+	}
+
+	if err := c.Delete(time.Date(2024, 11, 3, 4, 50, 0, 0, time.UTC), time.Date(2024, 11, 3, 6, 15, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	results, err = c.Get(start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := make(map[int]bool)
+	for _, r := range results {
+		kept[r.SomeInt] = true
+	}
+	if len(results) != n-9 || !kept[14] || kept[9] {
+		t.Fatalf("expected the EST half of the 01:00 bucket to survive a delete of its EDT half, got %v", kept)
+	}
+}
+
+func Test_FindOutOfOrderWrites(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for _, minute := range []int{90, 30, 10, 70, 50, 20, 80} {
+		if err := c.Store(base.Add(time.Duration(minute)*time.Minute), testStruct{SomeInt: minute}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ascending []int
+	err = c.Find(base, base.Add(2*time.Hour), func(_ time.Time, data testStruct) bool {
+		ascending = append(ascending, data.SomeInt)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []int{10, 20, 30, 50, 70, 80, 90}
+	if !slices.Equal(ascending, expected) {
+		t.Fatalf("expected ascending order %v, got %v", expected, ascending)
+	}
+
+	results, err := c.Get(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, minute := range []int{10, 20, 30, 50} {
+		if results[i].SomeInt != minute {
+			t.Fatalf("expected Get to return entries in ascending order, got %d at %d", results[i].SomeInt, i)
+		}
+	}
+}
+
+func Test_FindReverse(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for _, minute := range []int{90, 30, 10, 70, 50, 20, 80, 200} {
+		if err := c.Store(base.Add(time.Duration(minute)*time.Minute), testStruct{SomeInt: minute}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var descending []int
+	err = c.FindReverse(base, base.Add(2*time.Hour), func(_ time.Time, data testStruct) bool {
+		descending = append(descending, data.SomeInt)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []int{90, 80, 70, 50, 30, 20, 10}
+	if !slices.Equal(descending, expected) {
+		t.Fatalf("expected descending order %v, got %v", expected, descending)
+	}
+
+	var latest []int
+	for _, data := range c.AllReverse(base, base.Add(4*time.Hour)) {
+		latest = append(latest, data.SomeInt)
+		if len(latest) == 3 {
+			break
+		}
+	}
+	if !slices.Equal(latest, []int{200, 90, 80}) {
+		t.Fatalf("expected the 3 latest entries, got %v", latest)
+	}
+
+	results, err := c.GetReverse(base.Add(15*time.Minute), base.Add(55*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].SomeInt != 50 || results[2].SomeInt != 20 {
+		t.Fatalf("expected entries 50, 30 and 20, got %d results", len(results))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = c.FindReverseContext(ctx, base, base.Add(2*time.Hour), func(_ time.Time, _ testStruct) bool {
+		cancel()
+		return true
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
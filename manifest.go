@@ -0,0 +1,362 @@
+package timeseries
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ManifestVersion = 2
+
+	manifestName = ".timeseries.json"
+
+	EncodingCBOR       = "cbor"
+	EncodingCBORFramed = "cbor-framed"
+)
+
+var (
+	ErrManifestMismatch   = errors.New("store manifest does not match options")
+	ErrTypeMismatch       = errors.New("store was written with a different data type")
+	ErrUnsupportedVersion = errors.New("unsupported store format version")
+)
+
+type Manifest struct {
+	Version     int    `json:"version"`
+	Bucket      string `json:"bucket"`
+	Location    string `json:"location"`
+	Compression string `json:"compression"`
+	Encoding    string `json:"encoding"`
+	Type        string `json:"type,omitempty"`
+}
+
+var manifestMigrations = map[int]func(m *Manifest) error{
+	0: func(m *Manifest) error {
+		if m.Bucket == "" {
+			m.Bucket = time.Hour.String()
+		}
+		m.Compression = CompressionNone
+		m.Encoding = EncodingCBOR
+		return nil
+	},
+	1: func(m *Manifest) error {
+		m.Location = time.UTC.String()
+		return nil
+	},
+}
+
+func (c *Client[T]) newManifest() *Manifest {
+	m := &Manifest{
+		Version:     ManifestVersion,
+		Bucket:      c.bucket().String(),
+		Location:    c.location().String(),
+		Compression: c.compression(),
+		Encoding:    EncodingCBOR,
+	}
+	if c.Opts.Checksums {
+		m.Encoding = EncodingCBORFramed
+	}
+	if c.Opts.TypeCheck {
+		m.Type = typeFingerprint[T]()
+	}
+	return m
+}
+
+func (c *Client[T]) ensureManifest(adopt bool) error {
+	if c.manifestOK.Load() {
+		return nil
+	}
+
+	path := filepath.Join(c.Opts.Path, manifestName)
+	m, err := readManifest(path)
+	changed := false
+	if os.IsNotExist(err) {
+		legacy, err := c.hasData()
+		if err != nil {
+			return err
+		}
+
+		if legacy {
+			m, changed = new(Manifest), true
+		} else {
+			if c.Opts.ReadOnly {
+				return nil
+			}
+			if err := c.mkdirAll(c.Opts.Path); err != nil {
+				return err
+			}
+			err = writeManifestOnce(path, c.newManifest())
+			if err == nil {
+				c.manifestOK.Store(true)
+				return nil
+			}
+			if !os.IsExist(err) {
+				return err
+			}
+			m, err = readManifest(path)
+			if err != nil {
+				return err
+			}
+		}
+	} else if err != nil {
+		return err
+	}
+
+	migrated, err := c.migrateManifest(m)
+	if err != nil {
+		return err
+	}
+	changed = changed || migrated
+
+	if c.Opts.TypeCheck && m.Type == "" {
+		m.Type = typeFingerprint[T]()
+		changed = true
+	}
+
+	if err := c.checkManifest(m, adopt); err != nil {
+		return err
+	}
+
+	if changed && !c.Opts.ReadOnly {
+		if err := writeManifest(path, m); err != nil {
+			return err
+		}
+	}
+
+	c.manifestOK.Store(true)
+	return nil
+}
+
+func (c *Client[T]) hasData() (bool, error) {
+	entries, err := os.ReadDir(c.Opts.Path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c *Client[T]) migrateManifest(m *Manifest) (bool, error) {
+	if m.Version > ManifestVersion {
+		return false, fmt.Errorf("%w: store is version %d, this library supports up to %d", ErrUnsupportedVersion, m.Version, ManifestVersion)
+	}
+	if m.Version == ManifestVersion {
+		return false, nil
+	}
+
+	if c.Opts.Migrate != nil {
+		if err := c.Opts.Migrate(m, ManifestVersion); err != nil {
+			return false, fmt.Errorf("migrating store from version %d: %w", m.Version, err)
+		}
+	}
+
+	for m.Version < ManifestVersion {
+		migrate, ok := manifestMigrations[m.Version]
+		if !ok {
+			return false, fmt.Errorf("%w: no migration from version %d", ErrUnsupportedVersion, m.Version)
+		}
+		if err := migrate(m); err != nil {
+			return false, fmt.Errorf("migrating store from version %d: %w", m.Version, err)
+		}
+		m.Version++
+	}
+
+	return true, nil
+}
+
+func (c *Client[T]) checkManifest(m *Manifest, adopt bool) error {
+	bucket, err := time.ParseDuration(m.Bucket)
+	if err != nil {
+		return fmt.Errorf("invalid bucket %q in manifest: %w", m.Bucket, err)
+	}
+
+	if adopt && c.Opts.Bucket == 0 {
+		c.Opts.Bucket = bucket
+	}
+
+	if bucket != c.bucket() {
+		return fmt.Errorf("%w: store uses %s buckets, options use %s", ErrManifestMismatch, bucket, c.bucket())
+	}
+
+	if adopt && c.Opts.Location == nil {
+		location, err := time.LoadLocation(m.Location)
+		if err != nil {
+			return fmt.Errorf("invalid location %q in manifest: %w", m.Location, err)
+		}
+		c.Opts.Location = location
+	}
+
+	if m.Location != c.location().String() {
+		return fmt.Errorf("%w: store buckets in %s, options use %s", ErrManifestMismatch, m.Location, c.location())
+	}
+
+	if codecs[m.Compression] == nil {
+		return fmt.Errorf("%w: %q in manifest", ErrUnknownCompression, m.Compression)
+	}
+
+	if adopt && c.Opts.Compression == "" {
+		c.Opts.Compression = m.Compression
+	}
+
+	if m.Compression != c.compression() {
+		return fmt.Errorf("%w: store uses %s compression, options use %s", ErrManifestMismatch, m.Compression, c.compression())
+	}
+
+	switch m.Encoding {
+	case EncodingCBOR:
+		if c.Opts.Checksums {
+			return fmt.Errorf("%w: store uses %s encoding, options enable checksums", ErrManifestMismatch, m.Encoding)
+		}
+	case EncodingCBORFramed:
+		if adopt {
+			c.Opts.Checksums = true
+		}
+		if !c.Opts.Checksums {
+			return fmt.Errorf("%w: store uses %s encoding, options disable checksums", ErrManifestMismatch, m.Encoding)
+		}
+	default:
+		return fmt.Errorf("%w: unknown encoding %q", ErrManifestMismatch, m.Encoding)
+	}
+
+	if c.Opts.TypeCheck && m.Type != typeFingerprint[T]() {
+		return fmt.Errorf("%w: store has type %s, client uses %s (%s)", ErrTypeMismatch, m.Type, typeFingerprint[T](), reflect.TypeFor[T]())
+	}
+
+	return nil
+}
+
+func typeFingerprint[T any]() string {
+	var b strings.Builder
+	describeType(&b, reflect.TypeFor[T](), make(map[reflect.Type]bool))
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+func describeType(b *strings.Builder, t reflect.Type, seen map[reflect.Type]bool) {
+	if t.Kind() == reflect.Struct && t != reflect.TypeFor[time.Time]() {
+		if seen[t] {
+			b.WriteString("cycle:" + t.Name())
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		b.WriteString("struct{")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			b.WriteString(f.Name)
+			if tag := f.Tag.Get("cbor"); tag != "" {
+				b.WriteString(" `" + tag + "`")
+			}
+			b.WriteString(" ")
+			describeType(b, f.Type, seen)
+			b.WriteString(";")
+		}
+		b.WriteString("}")
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		b.WriteString("*")
+		describeType(b, t.Elem(), seen)
+	case reflect.Slice:
+		b.WriteString("[]")
+		describeType(b, t.Elem(), seen)
+	case reflect.Array:
+		fmt.Fprintf(b, "[%d]", t.Len())
+		describeType(b, t.Elem(), seen)
+	case reflect.Map:
+		b.WriteString("map[")
+		describeType(b, t.Key(), seen)
+		b.WriteString("]")
+		describeType(b, t.Elem(), seen)
+	default:
+		if t == reflect.TypeFor[time.Time]() {
+			b.WriteString("time.Time")
+			return
+		}
+		b.WriteString(t.Kind().String())
+	}
+}
+
+func readManifest(path string) (*Manifest, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := new(Manifest)
+	if err := json.Unmarshal(buf, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+func writeManifestTemp(path string, m *Manifest) (string, error) {
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), manifestName+".tmp*")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tmp.Write(append(buf, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+func writeManifestOnce(path string, m *Manifest) error {
+	tmp, err := writeManifestTemp(path, m)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	return os.Link(tmp, path)
+}
+
+func writeManifest(path string, m *Manifest) error {
+	tmp, err := writeManifestTemp(path, m)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	return os.Rename(tmp, path)
+}
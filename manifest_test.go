@@ -0,0 +1,329 @@
+package timeseries
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_ManifestWrittenOnInit(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := Init[testStruct](Options{Path: tmpDir, Bucket: 15 * time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := readManifest(filepath.Join(tmpDir, manifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Bucket != "15m0s" {
+		t.Fatalf("expected 15m0s bucket in manifest, got %q", m.Bucket)
+	}
+}
+
+func Test_ManifestWrittenOnFirstStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+
+	c, err := Init[testStruct](Options{Path: path, Bucket: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(path, manifestName)); !os.IsNotExist(err) {
+		t.Fatal("expected no manifest before the store directory exists")
+	}
+
+	if err := c.Store(time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), testStruct{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(path, manifestName)); err != nil {
+		t.Fatal("expected manifest after the first store")
+	}
+}
+
+func Test_ManifestBucketMismatch(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := Init[testStruct](Options{Path: tmpDir, Bucket: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Init[testStruct](Options{Path: tmpDir, Bucket: time.Hour})
+	if !errors.Is(err, ErrManifestMismatch) {
+		t.Fatalf("expected ErrManifestMismatch, got %v", err)
+	}
+}
+
+func Test_ManifestBucketAdopted(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c1, err := Init[testStruct](Options{Path: tmpDir, Bucket: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := c1.Store(when, testStruct{SomeInt: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	c2, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c2.Opts.Bucket != 24*time.Hour {
+		t.Fatalf("expected bucket to be adopted from manifest, got %s", c2.Opts.Bucket)
+	}
+
+	results, err := c2.Get(when, when)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SomeInt != 7 {
+		t.Fatal("expected to read data stored with the adopted bucket")
+	}
+}
+
+type otherStruct struct {
+	Name  string
+	Count int
+}
+
+func Test_ManifestContents(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := Init[testStruct](Options{Path: tmpDir, Checksums: true, TypeCheck: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := readManifest(filepath.Join(tmpDir, manifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Manifest{
+		Version:     ManifestVersion,
+		Bucket:      "1h0m0s",
+		Location:    "UTC",
+		Compression: CompressionNone,
+		Encoding:    EncodingCBORFramed,
+		Type:        typeFingerprint[testStruct](),
+	}
+	if *m != expected {
+		t.Fatalf("expected manifest %+v, got %+v", expected, *m)
+	}
+}
+
+func Test_ManifestTypeMismatch(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := Init[testStruct](Options{Path: tmpDir, TypeCheck: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Init[testStruct](Options{Path: tmpDir, TypeCheck: true}); err != nil {
+		t.Fatalf("expected the same type to open the store, got %v", err)
+	}
+
+	_, err := Init[otherStruct](Options{Path: tmpDir, TypeCheck: true})
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected ErrTypeMismatch, got %v", err)
+	}
+
+	if _, err := Init[otherStruct](Options{Path: tmpDir}); err != nil {
+		t.Fatalf("expected type check to be optional, got %v", err)
+	}
+}
+
+func Test_ManifestTypeRecordedLater(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := Init[testStruct](Options{Path: tmpDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Init[testStruct](Options{Path: tmpDir, TypeCheck: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Init[otherStruct](Options{Path: tmpDir, TypeCheck: true}); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("expected ErrTypeMismatch once the type is recorded, got %v", err)
+	}
+}
+
+func Test_TypeFingerprint(t *testing.T) {
+	if typeFingerprint[testStruct]() == typeFingerprint[otherStruct]() {
+		t.Fatal("expected different fingerprints for different types")
+	}
+
+	type renamed struct {
+		SomeString string
+		SomeInt    int
+		SomeFloat  float64
+	}
+	if typeFingerprint[testStruct]() != typeFingerprint[renamed]() {
+		t.Fatal("expected fingerprints to depend on structure, not names")
+	}
+
+	type node struct {
+		Next *node
+	}
+	if typeFingerprint[node]() == "" {
+		t.Fatal("expected fingerprint for recursive type")
+	}
+}
+
+func Test_ManifestEncoding(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if _, err := Init[testStruct](Options{Path: tmpDir, Checksums: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Opts.Checksums {
+		t.Fatal("expected checksums to be adopted from the manifest")
+	}
+
+	plainDir := t.TempDir()
+	if _, err := Init[testStruct](Options{Path: plainDir}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Init[testStruct](Options{Path: plainDir, Checksums: true})
+	if !errors.Is(err, ErrManifestMismatch) {
+		t.Fatalf("expected ErrManifestMismatch, got %v", err)
+	}
+}
+
+func Test_ManifestUnsupportedVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	m := &Manifest{Version: ManifestVersion + 1, Bucket: "1h0m0s", Compression: CompressionNone, Encoding: EncodingCBOR}
+	if err := writeManifest(filepath.Join(tmpDir, manifestName), m); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Init[testStruct](Options{Path: tmpDir})
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func Test_ManifestMigration(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, manifestName)
+
+	if err := os.WriteFile(path, []byte(`{"bucket": "15m0s"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var migratedFrom, migratedTo int
+	_, err := Init[testStruct](Options{
+		Path: tmpDir,
+		Migrate: func(m *Manifest, to int) error {
+			migratedFrom, migratedTo = m.Version, to
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if migratedFrom != 0 || migratedTo != ManifestVersion {
+		t.Fatalf("expected migration hook from 0 to %d, got %d to %d", ManifestVersion, migratedFrom, migratedTo)
+	}
+
+	m, err := readManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Manifest{Version: ManifestVersion, Bucket: "15m0s", Location: "UTC", Compression: CompressionNone, Encoding: EncodingCBOR}
+	if *m != expected {
+		t.Fatalf("expected migrated manifest %+v, got %+v", expected, *m)
+	}
+}
+
+func Test_ManifestMigrationHookError(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, manifestName), []byte(`{"bucket": "1h0m0s"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	hookErr := errors.New("refusing to migrate")
+	_, err := Init[testStruct](Options{
+		Path: tmpDir,
+		Migrate: func(m *Manifest, to int) error {
+			return hookErr
+		},
+	})
+	if !errors.Is(err, hookErr) {
+		t.Fatalf("expected migration hook error, got %v", err)
+	}
+}
+
+func Test_ManifestLegacyStore(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, manifestName)
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := c.Store(when, testStruct{SomeInt: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Init[testStruct](Options{Path: tmpDir, Bucket: time.Minute})
+	if !errors.Is(err, ErrManifestMismatch) {
+		t.Fatalf("expected ErrManifestMismatch for a legacy store, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected a rejected client to leave the store without a manifest")
+	}
+
+	readOnly, err := Init[testStruct](Options{Path: tmpDir, ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := readOnly.Get(when, when)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SomeInt != 7 {
+		t.Fatal("expected to read the legacy store")
+	}
+	if err := readOnly.Store(when, testStruct{}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("expected a read-only client not to write a manifest")
+	}
+
+	if _, err := Init[testStruct](Options{Path: tmpDir}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := readManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Manifest{Version: ManifestVersion, Bucket: "1h0m0s", Location: "UTC", Compression: CompressionNone, Encoding: EncodingCBOR}
+	if *m != expected {
+		t.Fatalf("expected the legacy manifest %+v, got %+v", expected, *m)
+	}
+}
@@ -0,0 +1,64 @@
+package timeseries
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+func (c *Client[T]) findParallel(ctx context.Context, buckets []time.Time, from time.Time, to time.Time, reverse bool, fn func(t time.Time, data T) bool) error {
+	type result struct {
+		entries []Entry[T]
+		err     error
+	}
+
+	readCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	results := make([]chan result, len(buckets))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+
+	slots := make(chan struct{}, c.Opts.ReadWorkers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, bucket := range buckets {
+			select {
+			case slots <- struct{}{}:
+			case <-readCtx.Done():
+				return
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				entries, err := c.loadBucket(readCtx, bucket, from, to, reverse)
+				results[i] <- result{entries: entries, err: err}
+			}()
+		}
+	}()
+
+	for i := range buckets {
+		var r result
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-slots
+
+		shouldContinue, err := deliverEntries(ctx, r.entries, fn)
+		if err != nil || !shouldContinue {
+			return err
+		}
+		if r.err != nil {
+			return r.err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package timeseries
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+func storeShuffledHours(t *testing.T, c *Client[testStruct], base time.Time, hours int) {
+	t.Helper()
+
+	for h := 0; h < hours; h++ {
+		for _, minute := range []int{40, 5, 25, 55, 15} {
+			when := base.Add(time.Duration(h)*time.Hour + time.Duration(minute)*time.Minute)
+			if err := c.Store(when, testStruct{SomeInt: h*60 + minute}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func Test_ParallelFind(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	tmpDir := t.TempDir()
+	base := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	sequential, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeShuffledHours(t, sequential, base, 48)
+
+	parallel, err := Init[testStruct](Options{Path: tmpDir, ReadWorkers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, reverse := range []bool{false, true} {
+		get := func(c *Client[testStruct]) []*testStruct {
+			results, err := c.Get(base, base.Add(48*time.Hour))
+			if reverse {
+				results, err = c.GetReverse(base, base.Add(48*time.Hour))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			return results
+		}
+
+		expected, actual := get(sequential), get(parallel)
+		if len(actual) != 48*5 || len(actual) != len(expected) {
+			t.Fatalf("expected %d results, got %d", len(expected), len(actual))
+		}
+		for i := range expected {
+			if actual[i].SomeInt != expected[i].SomeInt {
+				t.Fatalf("expected parallel reads to keep the order (reverse %v), got %d at %d, want %d", reverse, actual[i].SomeInt, i, expected[i].SomeInt)
+			}
+		}
+	}
+}
+
+func Test_ParallelFindEarlyStop(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	c, err := Init[testStruct](Options{Path: t.TempDir(), ReadWorkers: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	storeShuffledHours(t, c, base, 24)
+
+	var seen []int
+	err = c.Find(base, base.Add(24*time.Hour), func(_ time.Time, data testStruct) bool {
+		seen = append(seen, data.SomeInt)
+		return len(seen) < 7
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{5, 15, 25, 40, 55, 65, 75}
+	for i := range expected {
+		if seen[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, seen)
+		}
+	}
+}
+
+func Test_ParallelFindError(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	c, err := Init[testStruct](Options{Path: t.TempDir(), ReadWorkers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	storeShuffledHours(t, c, base, 12)
+	tearLastRecord(t, c.timeToPath(base.Add(5*time.Hour)))
+
+	count := 0
+	err = c.Find(base, base.Add(12*time.Hour), func(_ time.Time, _ testStruct) bool {
+		count++
+		return true
+	})
+
+	var tornErr *TornWriteError
+	if !errors.As(err, &tornErr) {
+		t.Fatalf("expected TornWriteError, got %v", err)
+	}
+	if count != 5*5+4 {
+		t.Fatalf("expected the entries before the torn record to be delivered, got %d", count)
+	}
+}
@@ -0,0 +1,360 @@
+package timeseries
+
+import (
+	"context"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+func (c *Client[T]) yearBuckets() int {
+	return 366 * 24 * 60 / c.bucketMinutes()
+}
+
+func (c *Client[T]) setPresentLocked(year int, index int) {
+	if c.Cache[year] == nil {
+		c.Cache[year] = make([]uint64, (c.yearBuckets()+63)/64)
+	}
+	c.Cache[year][index/64] |= uint64(1) << (index % 64)
+}
+
+func (c *Client[T]) clearPresentLocked(year int, index int) {
+	if words := c.Cache[year]; words != nil {
+		words[index/64] &^= uint64(1) << (index % 64)
+	}
+}
+
+func (c *Client[T]) presentLocked(year int, index int) bool {
+	words := c.Cache[year]
+	return words != nil && words[index/64]&(uint64(1)<<(index%64)) != 0
+}
+
+func nextBit(words []uint64, start int, end int) (int, bool) {
+	if words == nil {
+		return 0, false
+	}
+	for i := start; i <= end; {
+		if w := words[i/64] >> (i % 64); w != 0 {
+			i += bits.TrailingZeros64(w)
+			return i, i <= end
+		}
+		i = (i/64 + 1) * 64
+	}
+	return 0, false
+}
+
+func prevBit(words []uint64, start int, end int) (int, bool) {
+	if words == nil {
+		return 0, false
+	}
+	for i := end; i >= start; {
+		if w := words[i/64] << (63 - i%64); w != 0 {
+			i -= bits.LeadingZeros64(w)
+			return i, i >= start
+		}
+		i = i/64*64 - 1
+	}
+	return 0, false
+}
+
+func (c *Client[T]) cachedBucketsLocked() []time.Time {
+	var buckets []time.Time
+	for year, words := range c.Cache {
+		for i, ok := nextBit(words, 0, c.yearBuckets()-1); ok; i, ok = nextBit(words, i+1, c.yearBuckets()-1) {
+			buckets = append(buckets, c.cacheBucket(year, i))
+		}
+	}
+
+	slices.SortFunc(buckets, func(a, b time.Time) int {
+		return a.Compare(b)
+	})
+	return buckets
+}
+
+func (c *Client[T]) cacheAuthoritative() bool {
+	return c.Opts.LazyCache || c.cacheLoaded
+}
+
+func (c *Client[T]) nextCached(from time.Time, to time.Time) (time.Time, bool) {
+	if c.getCache(from) {
+		return from, true
+	}
+
+	fromYear, fromIndex := c.cacheKey(from)
+	toYear, toIndex := c.cacheKey(to)
+	for year := fromYear; year <= toYear; year++ {
+		start, end := 0, c.yearBuckets()-1
+		if year == fromYear {
+			start = fromIndex + 1
+		}
+		if year == toYear {
+			end = toIndex
+		}
+
+		c.loadYear(c.cacheBucket(year, 0))
+		c.mu.RLock()
+		i, ok := nextBit(c.Cache[year], start, end)
+		c.mu.RUnlock()
+		if ok {
+			return c.cacheBucket(year, i), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (c *Client[T]) prevCached(from time.Time, to time.Time) (time.Time, bool) {
+	if c.getCache(to) {
+		return to, true
+	}
+
+	fromYear, fromIndex := c.cacheKey(from)
+	toYear, toIndex := c.cacheKey(to)
+	for year := toYear; year >= fromYear; year-- {
+		start, end := 0, c.yearBuckets()-1
+		if year == fromYear {
+			start = fromIndex
+		}
+		if year == toYear {
+			end = toIndex - 1
+		}
+
+		c.loadYear(c.cacheBucket(year, 0))
+		c.mu.RLock()
+		i, ok := prevBit(c.Cache[year], start, end)
+		c.mu.RUnlock()
+		if ok {
+			return c.cacheBucket(year, i), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (c *Client[T]) seenDir(dir string, mtime int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dirTimes == nil {
+		c.dirTimes = make(map[string]int64)
+	}
+	c.dirTimes[dir] = mtime
+}
+
+func (c *Client[T]) dirSpan(t time.Time, level int) (string, time.Time, time.Time) {
+	t = t.In(c.location())
+	dir := filepath.Dir(c.timeToPath(t))
+	for range c.layoutDepth() - 1 - level {
+		dir = filepath.Dir(dir)
+	}
+
+	var start, next time.Time
+	switch level {
+	case 1:
+		start = time.Date(t.Year(), 1, 1, 0, 0, 0, 0, c.location())
+		next = start.AddDate(1, 0, 0)
+	case 2:
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, c.location())
+		next = start.AddDate(0, 1, 0)
+	case 3:
+		start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.location())
+		next = start.AddDate(0, 0, 1)
+	default:
+		start = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, c.location())
+		next = start.Add(time.Hour)
+	}
+	return dir, start, c.prevBucket(next)
+}
+
+func (c *Client[T]) refreshDir(t time.Time) (time.Time, time.Time, bool, error) {
+	if err := c.loadYear(t); err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+
+	leaf := c.layoutDepth() - 1
+	dir, start, end := c.dirSpan(t, leaf)
+	fi, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		for level := leaf - 1; level > 0; level-- {
+			parent, parentStart, parentEnd := c.dirSpan(t, level)
+			if _, err := os.Stat(parent); !os.IsNotExist(err) {
+				break
+			}
+			start, end = parentStart, parentEnd
+			if level == 1 {
+				if start, end, err = c.missingYears(t); err != nil {
+					return time.Time{}, time.Time{}, false, err
+				}
+			}
+		}
+
+		c.mu.Lock()
+		forgot := c.forgetLocked(start, end, nil)
+		c.mu.Unlock()
+		if forgot {
+			c.indexChanged(start)
+		}
+		return start, end, false, nil
+	}
+	if err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+
+	mtime := fi.ModTime().UnixNano()
+	c.mu.RLock()
+	seen, ok := c.dirTimes[dir]
+	c.mu.RUnlock()
+	if ok && seen == mtime {
+		return start, end, true, nil
+	}
+
+	if err := c.rescanDir(dir, start, end); err != nil {
+		return time.Time{}, time.Time{}, false, err
+	}
+	c.seenDir(dir, mtime)
+	return start, end, true, nil
+}
+
+func (c *Client[T]) rescanDir(dir string, start time.Time, end time.Time) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	files := make(map[string]os.FileInfo)
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !c.isDataFile(path) {
+			continue
+		}
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		files[path] = info
+	}
+
+	stamps := c.bucketStamps(files)
+	c.mu.Lock()
+	c.forgetLocked(start, end, stamps)
+	c.mu.Unlock()
+	c.reconcileStats(stamps)
+	c.indexChanged(start)
+	return nil
+}
+
+func (c *Client[T]) missingYears(t time.Time) (time.Time, time.Time, error) {
+	entries, err := os.ReadDir(c.Opts.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return time.Time{}, time.Time{}, err
+	}
+
+	year := t.In(c.location()).Year()
+	start, end := time.Time{}, endOfTime
+	for _, entry := range entries {
+		y, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		if first := time.Date(y+1, 1, 1, 0, 0, 0, 0, c.location()); y < year && first.After(start) {
+			start = first
+		}
+		if last := c.prevBucket(time.Date(y, 1, 1, 0, 0, 0, 0, c.location())); y > year && last.Before(end) {
+			end = last
+		}
+	}
+	return start, end, nil
+}
+
+func (c *Client[T]) forgetLocked(start time.Time, end time.Time, keep map[int64]bucketStats) bool {
+	startYear, from := c.cacheKey(start)
+	endYear, to := c.cacheKey(end)
+
+	forgot := false
+	for year, words := range c.Cache {
+		if year < startYear || year > endYear {
+			continue
+		}
+		first, last := 0, c.yearBuckets()-1
+		if year == startYear {
+			first = from
+		}
+		if year == endYear {
+			last = to
+		}
+
+		for i, ok := nextBit(words, first, last); ok; i, ok = nextBit(words, i+1, last) {
+			bucket := c.cacheBucket(year, i)
+			if _, ok := keep[bucket.Unix()]; !ok {
+				c.clearPresentLocked(year, i)
+				delete(c.stats, bucket.Unix())
+				forgot = true
+			}
+		}
+	}
+	return forgot
+}
+
+func (c *Client[T]) eachCached(ctx context.Context, from time.Time, to time.Time, reverse bool, fn func(bucket time.Time) (bool, error)) error {
+	var syncedStart, syncedEnd time.Time
+	for !from.After(to) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		current := from
+		if reverse {
+			current = to
+		}
+
+		start, end, found := syncedStart, syncedEnd, true
+		if syncedStart.IsZero() || current.Before(syncedStart) || current.After(syncedEnd) {
+			var err error
+			start, end, found, err = c.refreshDir(current)
+			if err != nil {
+				return err
+			}
+			syncedStart, syncedEnd = start, end
+			if !found {
+				syncedStart = time.Time{}
+			}
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+
+		var bucket time.Time
+		if found {
+			if reverse {
+				bucket, found = c.prevCached(start, end)
+			} else {
+				bucket, found = c.nextCached(start, end)
+			}
+		}
+		if !found {
+			if reverse {
+				to = c.prevBucket(start)
+			} else {
+				from = c.nextBucket(end)
+			}
+			continue
+		}
+
+		shouldContinue, err := fn(bucket)
+		if err != nil || !shouldContinue {
+			return err
+		}
+		if reverse {
+			to = c.prevBucket(bucket)
+		} else {
+			from = c.nextBucket(bucket)
+		}
+	}
+	return nil
+}
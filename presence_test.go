@@ -0,0 +1,129 @@
+package timeseries
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_PresenceBits(t *testing.T) {
+	words := make([]uint64, 4)
+	for _, i := range []int{0, 63, 64, 130, 255} {
+		words[i/64] |= uint64(1) << (i % 64)
+	}
+
+	var forward []int
+	for i, ok := nextBit(words, 0, 255); ok; i, ok = nextBit(words, i+1, 255) {
+		forward = append(forward, i)
+	}
+	if !slices.Equal(forward, []int{0, 63, 64, 130, 255}) {
+		t.Fatalf("unexpected forward scan %v", forward)
+	}
+
+	var backward []int
+	for i, ok := prevBit(words, 0, 255); ok; i, ok = prevBit(words, 0, i-1) {
+		backward = append(backward, i)
+	}
+	if !slices.Equal(backward, []int{255, 130, 64, 63, 0}) {
+		t.Fatalf("unexpected backward scan %v", backward)
+	}
+
+	if _, ok := nextBit(words, 65, 129); ok {
+		t.Fatal("expected no bits between 65 and 129")
+	}
+	if _, ok := prevBit(words, 65, 129); ok {
+		t.Fatal("expected no bits between 65 and 129 scanning backward")
+	}
+	if _, ok := nextBit(nil, 0, 10); ok {
+		t.Fatal("expected no bits in an empty year")
+	}
+}
+
+func Test_FindJumpsBetweenBuckets(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Bucket: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	times := []time.Time{
+		time.Date(2019, 12, 31, 23, 59, 0, 0, time.UTC),
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 7, 4, 12, 30, 0, 0, time.UTC),
+		time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC),
+	}
+	for i, ts := range times {
+		if err := c.Store(ts, testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if c.getCache(times[0].Add(time.Minute * 2)) {
+		t.Fatal("expected an empty minute to be absent")
+	}
+
+	from := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var forward []int
+	if err := c.Find(from, to, func(ts time.Time, data testStruct) bool {
+		forward = append(forward, data.SomeInt)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(forward, []int{0, 1, 2, 3}) {
+		t.Fatalf("expected every stored minute in order, got %v", forward)
+	}
+
+	var backward []int
+	if err := c.FindReverse(times[1], times[3], func(ts time.Time, data testStruct) bool {
+		backward = append(backward, data.SomeInt)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(backward, []int{3, 2, 1}) {
+		t.Fatalf("expected the range in reverse, got %v", backward)
+	}
+
+	other, err := Init[testStruct](Options{Path: tmpDir, Bucket: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	late := time.Date(2023, 3, 1, 8, 0, 0, 0, time.UTC)
+	if err := other.Store(late, testStruct{SomeInt: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Get(late, late)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SomeInt != 4 {
+		t.Fatalf("expected a bucket created by another client to be found, got %v", results)
+	}
+
+	if err := other.Store(times[0].Add(time.Minute*2), testStruct{SomeInt: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(c.timeToPath(times[3])); err != nil {
+		t.Fatal(err)
+	}
+
+	forward = forward[:0]
+	if err := c.Find(from, to, func(ts time.Time, data testStruct) bool {
+		forward = append(forward, data.SomeInt)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(forward, []int{0, 1, 5, 2, 4}) {
+		t.Fatalf("expected changes by another client to be picked up, got %v", forward)
+	}
+	if c.getCache(times[3]) {
+		t.Fatal("expected a bucket removed by another client to be dropped from the bitset")
+	}
+}
@@ -0,0 +1,91 @@
+package timeseries
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type RebucketReport struct {
+	Path      string   `json:"path"`
+	Files     int      `json:"files"`
+	Rewritten int      `json:"rewritten"`
+	Moved     int      `json:"moved"`
+	Skipped   []string `json:"skipped"`
+}
+
+var endOfTime = time.Unix(1<<62, 0)
+
+func (c *Client[T]) Rebucket(ctx context.Context) (*RebucketReport, error) {
+	if err := c.writable(); err != nil {
+		return nil, err
+	}
+
+	report := &RebucketReport{
+		Path:    c.Opts.Path,
+		Skipped: []string{},
+	}
+
+	if _, err := os.Stat(c.Opts.Path); os.IsNotExist(err) {
+		return report, nil
+	}
+
+	var paths []string
+	err := filepath.Walk(c.Opts.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && c.isDataFile(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		report.Files++
+		bucket, err := c.parsePathToTime(path)
+		if err != nil {
+			report.Skipped = append(report.Skipped, path)
+			continue
+		}
+
+		var moved []Entry[T]
+		_, err = c.readFile(ctx, path, time.Time{}, endOfTime, func(t time.Time, data T) bool {
+			if !c.bucketStart(t).Equal(bucket) {
+				moved = append(moved, Entry[T]{Time: t, Data: data})
+			}
+			return true
+		})
+		if err != nil {
+			return report, err
+		}
+
+		if len(moved) == 0 {
+			continue
+		}
+
+		if err := c.storeBatch(ctx, moved, nil); err != nil {
+			return report, err
+		}
+
+		removed, err := c.rewriteFile(bucket, func(entry Entry[T]) bool {
+			return c.bucketStart(entry.Time).Equal(bucket)
+		})
+		if err != nil {
+			return report, err
+		}
+
+		report.Rewritten++
+		report.Moved += removed
+	}
+
+	return report, nil
+}
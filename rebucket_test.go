@@ -0,0 +1,136 @@
+package timeseries
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeLegacyStore(t *testing.T, dir string, zone *time.Location, times []time.Time) {
+	t.Helper()
+
+	legacy, err := Init[testStruct](Options{Path: dir, Location: zone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, when := range times {
+		if err := legacy.Store(when, testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := legacy.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, manifestName), []byte(`{"version": 1, "bucket": "1h0m0s", "compression": "none", "encoding": "cbor"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Rebucket(t *testing.T) {
+	tmpDir := t.TempDir()
+	zone := time.FixedZone("UTC+2", 2*3600)
+
+	base := time.Date(2024, 6, 15, 10, 15, 0, 0, time.UTC)
+	times := []time.Time{base, base.Add(20 * time.Minute), base.Add(time.Hour)}
+	writeLegacyStore(t, tmpDir, zone, times)
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Get(base, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == len(times) {
+		t.Fatal("expected legacy local time files to be missed before rebucketing")
+	}
+
+	report, err := c.Rebucket(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Files != 2 || report.Rewritten != 2 || report.Moved != len(times) {
+		t.Fatalf("expected 2 files rewritten and %d entries moved, got %+v", len(times), report)
+	}
+
+	for _, name := range []string{"10.cbor", "11.cbor"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, "2024", "06", "15", name)); err != nil {
+			t.Fatalf("expected UTC hour file %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"12.cbor", "13.cbor"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, "2024", "06", "15", name)); !os.IsNotExist(err) {
+			t.Fatalf("expected local hour file %s to be removed", name)
+		}
+	}
+
+	results, err = c.Get(base, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(times) {
+		t.Fatalf("expected %d results after rebucketing, got %d", len(times), len(results))
+	}
+
+	verify, err := c.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verify.Issues) != 0 {
+		t.Fatalf("expected no issues after rebucketing, got %+v", verify.Issues)
+	}
+
+	again, err := c.Rebucket(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Rewritten != 0 || again.Moved != 0 {
+		t.Fatalf("expected second rebucket to be a no-op, got %+v", again)
+	}
+}
+
+func Test_RebucketKeepsCorrectEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 15, 0, 0, time.UTC)
+	if err := c.Store(base, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := newRecordEncoder[testStruct](&buf, false).Encode(Entry[testStruct]{Time: base.Add(3 * time.Hour), Data: testStruct{SomeInt: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.appendFile(c.timeToPath(base), buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := c.Rebucket(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Moved != 1 {
+		t.Fatalf("expected 1 moved entry, got %+v", report)
+	}
+
+	for _, when := range []time.Time{base, base.Add(3 * time.Hour)} {
+		results, err := c.Get(c.bucketStart(when), c.nextBucket(c.bucketStart(when)).Add(-time.Nanosecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("expected 1 result in the bucket of %s, got %d", when, len(results))
+		}
+	}
+}
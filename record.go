@@ -0,0 +1,200 @@
+package timeseries
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	frameMarker     = 0xfd
+	frameHeaderSize = 9
+)
+
+var (
+	ErrChecksumMismatch = errors.New("record checksum mismatch")
+	ErrInvalidFrame     = errors.New("record frame runs past the end of a file with later records")
+	ErrMissingFrame     = errors.New("record frame marker missing")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+type CorruptRecordError struct {
+	Path   string
+	Offset int64
+	Err    error
+}
+
+func (e *CorruptRecordError) Error() string {
+	return fmt.Sprintf("corrupt record in %s at offset %d: %v", e.Path, e.Offset, e.Err)
+}
+
+func (e *CorruptRecordError) Unwrap() error {
+	return e.Err
+}
+
+type CorruptionPolicy int
+
+const (
+	CorruptStrict CorruptionPolicy = iota
+	CorruptSkip
+)
+
+type recordEncoder[T any] struct {
+	out     *bytes.Buffer
+	payload bytes.Buffer
+	enc     *cbor.Encoder
+	framed  bool
+}
+
+func newRecordEncoder[T any](out *bytes.Buffer, framed bool) *recordEncoder[T] {
+	e := &recordEncoder[T]{out: out, framed: framed}
+	if framed {
+		e.enc = cbor.NewEncoder(&e.payload)
+	} else {
+		e.enc = cbor.NewEncoder(out)
+	}
+	return e
+}
+
+func (e *recordEncoder[T]) Encode(entry Entry[T]) error {
+	if !e.framed {
+		return e.enc.Encode(entry)
+	}
+
+	e.payload.Reset()
+	if err := e.enc.Encode(entry); err != nil {
+		return err
+	}
+
+	var header [frameHeaderSize]byte
+	header[0] = frameMarker
+	binary.BigEndian.PutUint32(header[1:5], uint32(e.payload.Len()))
+	binary.BigEndian.PutUint32(header[5:9], lengthSum(header[1:5], e.payload.Bytes()))
+	e.out.Write(header[:])
+	e.out.Write(e.payload.Bytes())
+	return nil
+}
+
+type recordReader[T any] struct {
+	buf         []byte
+	off         int
+	framed      bool
+	skipCorrupt bool
+	corrupt     int
+	onCorrupt   func(offset int64, err error)
+}
+
+func (r *recordReader[T]) next() (Entry[T], error) {
+	for {
+		var entry Entry[T]
+		if r.off >= len(r.buf) {
+			return entry, io.EOF
+		}
+
+		if r.buf[r.off] != frameMarker && !r.framed {
+			rest, err := cbor.UnmarshalFirst(r.buf[r.off:], &entry)
+			if err != nil {
+				return entry, r.decodeErr(err)
+			}
+			r.off = len(r.buf) - len(rest)
+			return entry, nil
+		}
+
+		end, err := frameAt(r.buf, r.off)
+		if err == nil {
+			err = cbor.Unmarshal(r.buf[r.off+frameHeaderSize:end], &entry)
+		} else {
+			end = nextFrame(r.buf, r.off+1)
+			if end < 0 && (errors.Is(err, io.ErrUnexpectedEOF) || err == ErrMissingFrame) {
+				return entry, io.ErrUnexpectedEOF
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = ErrInvalidFrame
+			}
+			if end < 0 {
+				end = len(r.buf)
+			}
+		}
+		if err != nil {
+			if !r.skipCorrupt {
+				return entry, &CorruptRecordError{Offset: int64(r.off), Err: err}
+			}
+			r.corrupt++
+			if r.onCorrupt != nil {
+				r.onCorrupt(int64(r.off), err)
+			}
+			r.off = end
+			continue
+		}
+
+		r.off = end
+		return entry, nil
+	}
+}
+
+func lengthSum(length []byte, data []byte) uint32 {
+	return crc32.Update(crc32.Checksum(length, crcTable), crcTable, data)
+}
+
+func frameAt(buf []byte, off int) (int, error) {
+	if buf[off] != frameMarker {
+		return 0, ErrMissingFrame
+	}
+	if len(buf)-off < frameHeaderSize {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	length := buf[off+1 : off+5]
+	end := off + frameHeaderSize + int(binary.BigEndian.Uint32(length))
+	if end > len(buf) || end < off {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if lengthSum(length, buf[off+frameHeaderSize:end]) != binary.BigEndian.Uint32(buf[off+5:off+9]) {
+		return end, ErrChecksumMismatch
+	}
+	return end, nil
+}
+
+func nextFrame(buf []byte, from int) int {
+	for off := from; off < len(buf); off++ {
+		if buf[off] != frameMarker {
+			continue
+		}
+		if _, err := frameAt(buf, off); err == nil {
+			return off
+		}
+	}
+	return -1
+}
+
+func (r *recordReader[T]) decodeErr(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return &CorruptRecordError{Offset: int64(r.off), Err: err}
+}
+
+func decodeEntries[T any](r *recordReader[T], fn func(entry Entry[T]) bool) (int64, error) {
+	for {
+		valid := int64(r.off)
+
+		entry, err := r.next()
+		if err == io.EOF {
+			return valid, nil
+		}
+		if err != nil {
+			return valid, err
+		}
+
+		if !fn(entry) {
+			return int64(r.off), nil
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package timeseries
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func flipByte(t *testing.T, path string, offset int64) {
+	t.Helper()
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf[offset] ^= 0x01
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ChecksumsRoundTrip(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, baseTime, 3)
+
+	entries := []Entry[testStruct]{
+		{Time: baseTime.Add(time.Minute), Data: testStruct{SomeInt: 3}},
+		{Time: baseTime.Add(2 * time.Minute), Data: testStruct{SomeInt: 4}},
+	}
+	if err := c.StoreBatch(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := os.ReadFile(c.timeToPath(baseTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf[0] != frameMarker {
+		t.Fatal("expected framed records on disk")
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+
+	for i, r := range results {
+		if r.SomeInt != i {
+			t.Fatalf("expected SomeInt=%d, got %d", i, r.SomeInt)
+		}
+	}
+}
+
+func Test_ChecksumMismatchStrict(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	sizes := storeAndRecordSizes(t, c, baseTime, 3)
+	flipByte(t, c.timeToPath(baseTime), sizes[0]+frameHeaderSize+2)
+
+	_, err = c.Get(baseTime, baseTime.Add(time.Hour))
+
+	var corruptErr *CorruptRecordError
+	if !errors.As(err, &corruptErr) {
+		t.Fatalf("expected CorruptRecordError, got %v", err)
+	}
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if corruptErr.Offset != sizes[0] || corruptErr.Path != c.timeToPath(baseTime) {
+		t.Fatalf("unexpected corrupt record location: %+v", corruptErr)
+	}
+}
+
+func Test_ChecksumMismatchSkip(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true, Corruption: CorruptSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	sizes := storeAndRecordSizes(t, c, baseTime, 3)
+	flipByte(t, c.timeToPath(baseTime), sizes[0]+frameHeaderSize+2)
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].SomeInt != 0 || results[1].SomeInt != 2 {
+		t.Fatal("expected the corrupt middle record to be skipped")
+	}
+	if c.CorruptRecords() != 1 {
+		t.Fatalf("expected 1 corrupt record counted, got %d", c.CorruptRecords())
+	}
+}
+
+func Test_ChecksumsTornWrite(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true, RecoverTornWrites: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	sizes := storeAndRecordSizes(t, c, baseTime, 3)
+	tearLastRecord(t, c.timeToPath(baseTime))
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	removed, err := c.Repair(baseTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != sizes[2]-3-sizes[1] {
+		t.Fatalf("unexpected number of bytes removed: %d", removed)
+	}
+}
+
+func Test_PlainRecordsInFramedStore(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true, Corruption: CorruptSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		entry := Entry[testStruct]{Time: baseTime.Add(time.Duration(i) * time.Minute), Data: testStruct{SomeInt: i}}
+		if i%2 == 1 {
+			if err := c.Store(entry.Time, entry.Data); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		encoded, err := cbor.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.appendFile(c.timeToPath(baseTime), encoded); err != nil {
+			t.Fatal(err)
+		}
+		c.setCache(baseTime)
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].SomeInt != 1 || results[1].SomeInt != 3 {
+		t.Fatalf("expected only the framed records, got %v", results)
+	}
+	if c.CorruptRecords() != 2 {
+		t.Fatalf("expected 2 corrupt records counted, got %d", c.CorruptRecords())
+	}
+}
+
+func Test_ChecksumCorruptLength(t *testing.T) {
+	for _, offset := range []int64{3, 4} {
+		c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true, Corruption: CorruptSkip, RecoverTornWrites: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+		sizes := storeAndRecordSizes(t, c, baseTime, 5)
+		path := c.timeToPath(baseTime)
+		flipByte(t, path, offset)
+
+		results, err := c.Get(baseTime, baseTime.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 4 || results[0].SomeInt != 1 {
+			t.Fatalf("expected the records after the damaged length to be read, got %v", results)
+		}
+		if c.CorruptRecords() != 1 {
+			t.Fatalf("expected 1 corrupt record counted, got %d", c.CorruptRecords())
+		}
+
+		strict, err := Init[testStruct](Options{Path: c.Opts.Path, Checksums: true, RecoverTornWrites: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var corruptErr *CorruptRecordError
+		if _, err := strict.Get(baseTime, baseTime.Add(time.Hour)); !errors.As(err, &corruptErr) {
+			t.Fatalf("expected CorruptRecordError, got %v", err)
+		}
+
+		if _, err := c.Repair(baseTime); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() != sizes[4] {
+			t.Fatalf("expected repair to keep all %d bytes, got %d", sizes[4], fi.Size())
+		}
+	}
+}
+
+func Test_ChecksumDamagedMarker(t *testing.T) {
+	for _, marker := range []byte{0x7b, 0x7d, 0xed, 0xf5} {
+		c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true, Corruption: CorruptSkip, RecoverTornWrites: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+		sizes := storeAndRecordSizes(t, c, baseTime, 5)
+		path := c.timeToPath(baseTime)
+
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf[sizes[0]] = marker
+		if err := os.WriteFile(path, buf, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := c.Get(baseTime, baseTime.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("marker %#x: %v", marker, err)
+		}
+		if len(results) != 4 || results[1].SomeInt != 2 {
+			t.Fatalf("marker %#x: expected the records around the damaged marker, got %v", marker, results)
+		}
+		if c.CorruptRecords() != 1 {
+			t.Fatalf("marker %#x: expected 1 corrupt record counted, got %d", marker, c.CorruptRecords())
+		}
+
+		strict, err := Init[testStruct](Options{Path: c.Opts.Path, Checksums: true, RecoverTornWrites: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := strict.Get(baseTime, baseTime.Add(time.Hour)); !errors.Is(err, ErrMissingFrame) {
+			t.Fatalf("marker %#x: expected ErrMissingFrame, got %v", marker, err)
+		}
+	}
+}
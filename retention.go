@@ -0,0 +1,116 @@
+package timeseries
+
+import (
+	"errors"
+	"time"
+)
+
+type RetentionReport struct {
+	Horizon time.Time
+	Removed []time.Time
+	Bytes   int64
+}
+
+func (c *Client[T]) EnforceRetention() (*RetentionReport, error) {
+	if err := c.writable(); err != nil {
+		return nil, err
+	}
+
+	report := &RetentionReport{}
+	if c.Opts.Path == "" {
+		return report, nil
+	}
+
+	if err := c.loadYears(); err != nil {
+		return report, err
+	}
+	buckets := c.cachedBuckets()
+
+	if c.Opts.Retention > 0 {
+		report.Horizon = time.Now().Add(-c.Opts.Retention)
+		for len(buckets) > 0 && !c.nextBucket(buckets[0]).After(report.Horizon) {
+			if err := c.removeBucket(buckets[0], report); err != nil {
+				return report, err
+			}
+			buckets = buckets[1:]
+		}
+	}
+
+	if c.Opts.MaxBytes > 0 {
+		sizes := make([]int64, len(buckets))
+		var total int64
+		for i, bucket := range buckets {
+			size, err := c.bucketSize(bucket)
+			if err != nil {
+				return report, err
+			}
+			sizes[i] = size
+			total += size
+		}
+
+		for i := 0; i < len(buckets) && total > c.Opts.MaxBytes; i++ {
+			if err := c.removeBucket(buckets[i], report); err != nil {
+				return report, err
+			}
+			total -= sizes[i]
+		}
+	}
+
+	return report, nil
+}
+
+func (c *Client[T]) removeBucket(bucket time.Time, report *RetentionReport) error {
+	size, err := c.bucketSize(bucket)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Delete(bucket, c.nextBucket(bucket).Add(-time.Nanosecond)); err != nil {
+		return err
+	}
+
+	if size > 0 {
+		report.Removed = append(report.Removed, bucket)
+		report.Bytes += size
+	}
+	return nil
+}
+
+func (c *Client[T]) bucketSize(bucket time.Time) (int64, error) {
+	stamp, err := c.bucketStamp(bucket)
+	return stamp.Size, err
+}
+
+func (c *Client[T]) cachedBuckets() []time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cachedBucketsLocked()
+}
+
+func (c *Client[T]) retentionLoop() {
+	defer c.wg.Done()
+
+	interval := c.Opts.RetentionInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		report, err := c.EnforceRetention()
+		if errors.Is(err, ErrClosed) {
+			return
+		}
+		if c.Opts.OnRetention != nil {
+			c.Opts.OnRetention(report, err)
+		}
+	}
+}
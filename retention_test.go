@@ -0,0 +1,139 @@
+package timeseries
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_EnforceRetentionByAge(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Retention: 48 * time.Hour, RetentionInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	now := time.Now().UTC()
+	old := []time.Time{now.Add(-72 * time.Hour), now.Add(-50 * time.Hour)}
+	for _, tm := range append(old, now.Add(-time.Hour)) {
+		if err := c.Store(tm, testStruct{SomeInt: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := c.EnforceRetention()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Removed) != 2 {
+		t.Fatalf("expected 2 hours removed, got %v", report.Removed)
+	}
+
+	for i, hour := range report.Removed {
+		if !hour.Equal(old[i].Truncate(time.Hour)) {
+			t.Fatalf("expected %s removed, got %s", old[i].Truncate(time.Hour), hour)
+		}
+		if _, err := os.Stat(c.timeToPath(hour)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed from disk", hour)
+		}
+	}
+
+	if report.Bytes == 0 {
+		t.Fatal("expected removed bytes to be reported")
+	}
+
+	results, err := c.Get(now.Add(-100*time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result within retention, got %d", len(results))
+	}
+}
+
+func Test_EnforceRetentionByMaxBytes(t *testing.T) {
+	baseTime := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := c.Store(baseTime.Add(time.Duration(i)*time.Hour), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fi, err := os.Stat(c.timeToPath(baseTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Opts.MaxBytes = fi.Size() * 2
+	report, err := c.EnforceRetention()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Removed) != 3 || report.Bytes != fi.Size()*3 {
+		t.Fatalf("expected 3 hours and %d bytes removed, got %v and %d", fi.Size()*3, report.Removed, report.Bytes)
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(5*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].SomeInt != 3 {
+		t.Fatal("expected the two newest hours to remain")
+	}
+}
+
+func Test_RetentionLoopStopsOnClose(t *testing.T) {
+	reports := make(chan *RetentionReport, 100)
+
+	c, err := Init[testStruct](Options{
+		Path:              t.TempDir(),
+		Retention:         time.Hour,
+		RetentionInterval: 5 * time.Millisecond,
+		OnRetention: func(report *RetentionReport, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			reports <- report
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-3 * time.Hour)
+	if err := c.Store(old, testStruct{}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for removed := false; !removed; {
+		select {
+		case report := <-reports:
+			removed = len(report.Removed) == 1
+		case <-deadline:
+			t.Fatal("expected background retention to remove the old hour")
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for len(reports) > 0 {
+		<-reports
+	}
+	time.Sleep(20 * time.Millisecond)
+	if len(reports) != 0 {
+		t.Fatal("expected no retention runs after Close")
+	}
+}
@@ -0,0 +1,627 @@
+package timeseries
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sealedExt          = ".sealed"
+	sealMagic          = "TSSEALED"
+	sealBlockEntries   = 1024
+	sealIndexEntrySize = 28
+	sealFooterSize     = 49
+)
+
+var (
+	ErrInvalidSealedFile = errors.New("invalid sealed file")
+
+	sealCodecs = []string{CompressionNone, CompressionGzip, CompressionZstd, CompressionSnappy}
+)
+
+type CompactReport struct {
+	Before     time.Time
+	Sealed     []time.Time
+	Entries    int
+	Duplicates int
+	Bytes      int64
+	Failed     map[time.Time]error
+}
+
+type sealedRecord struct {
+	time time.Time
+	raw  []byte
+}
+
+var (
+	minSealTime = time.Unix(0, math.MinInt64)
+	maxSealTime = time.Unix(0, math.MaxInt64)
+)
+
+func sealNanos(t time.Time) int64 {
+	switch {
+	case t.Before(minSealTime):
+		return math.MinInt64
+	case t.After(maxSealTime):
+		return math.MaxInt64
+	}
+	return t.UnixNano()
+}
+
+type sealFooter struct {
+	min         int64
+	max         int64
+	count       uint64
+	indexOffset uint64
+	blocks      uint32
+	codec       uint8
+}
+
+type sealBlock struct {
+	min    int64
+	offset uint64
+	size   uint32
+	count  uint32
+	sum    uint32
+}
+
+func encodeSealed(records []sealedRecord, compression string) ([]byte, error) {
+	codec := codecs[compression]
+
+	var out bytes.Buffer
+	var index []sealBlock
+	for start := 0; start < len(records); start += sealBlockEntries {
+		end := min(start+sealBlockEntries, len(records))
+
+		var plain bytes.Buffer
+		for _, record := range records[start:end] {
+			plain.Write(record.raw)
+		}
+
+		data := plain.Bytes()
+		if codec.compress != nil {
+			var block bytes.Buffer
+			if err := codec.compress(&block, data); err != nil {
+				return nil, err
+			}
+			data = block.Bytes()
+		}
+
+		index = append(index, sealBlock{
+			min:    sealNanos(records[start].time),
+			offset: uint64(out.Len()),
+			size:   uint32(len(data)),
+			count:  uint32(end - start),
+			sum:    crc32.Checksum(data, crcTable),
+		})
+		out.Write(data)
+	}
+
+	footer := sealFooter{
+		count:       uint64(len(records)),
+		indexOffset: uint64(out.Len()),
+		blocks:      uint32(len(index)),
+		codec:       uint8(slices.Index(sealCodecs, compression)),
+	}
+	if len(records) > 0 {
+		footer.min = sealNanos(records[0].time)
+		footer.max = sealNanos(records[len(records)-1].time)
+	}
+
+	tail := make([]byte, 0, len(index)*sealIndexEntrySize+sealFooterSize)
+	for _, b := range index {
+		tail = binary.BigEndian.AppendUint64(tail, uint64(b.min))
+		tail = binary.BigEndian.AppendUint64(tail, b.offset)
+		tail = binary.BigEndian.AppendUint32(tail, b.size)
+		tail = binary.BigEndian.AppendUint32(tail, b.count)
+		tail = binary.BigEndian.AppendUint32(tail, b.sum)
+	}
+	tail = binary.BigEndian.AppendUint64(tail, uint64(footer.min))
+	tail = binary.BigEndian.AppendUint64(tail, uint64(footer.max))
+	tail = binary.BigEndian.AppendUint64(tail, footer.count)
+	tail = binary.BigEndian.AppendUint64(tail, footer.indexOffset)
+	tail = binary.BigEndian.AppendUint32(tail, footer.blocks)
+	tail = append(tail, footer.codec)
+	tail = binary.BigEndian.AppendUint32(tail, crc32.Checksum(tail, crcTable))
+	tail = append(tail, sealMagic...)
+	out.Write(tail)
+
+	return out.Bytes(), nil
+}
+
+func readSealedIndex(r io.ReaderAt, size int64) (*sealFooter, []sealBlock, error) {
+	if size < sealFooterSize {
+		return nil, nil, fmt.Errorf("%w: %d bytes is too short for a footer", ErrInvalidSealedFile, size)
+	}
+
+	buf := make([]byte, sealFooterSize)
+	if _, err := r.ReadAt(buf, size-sealFooterSize); err != nil {
+		return nil, nil, err
+	}
+	if string(buf[sealFooterSize-len(sealMagic):]) != sealMagic {
+		return nil, nil, fmt.Errorf("%w: missing magic", ErrInvalidSealedFile)
+	}
+
+	footer := &sealFooter{
+		min:         int64(binary.BigEndian.Uint64(buf[0:8])),
+		max:         int64(binary.BigEndian.Uint64(buf[8:16])),
+		count:       binary.BigEndian.Uint64(buf[16:24]),
+		indexOffset: binary.BigEndian.Uint64(buf[24:32]),
+		blocks:      binary.BigEndian.Uint32(buf[32:36]),
+		codec:       buf[36],
+	}
+	sum := binary.BigEndian.Uint32(buf[37:41])
+
+	indexSize := uint64(footer.blocks) * sealIndexEntrySize
+	if footer.indexOffset+indexSize+sealFooterSize != uint64(size) {
+		return nil, nil, fmt.Errorf("%w: index does not match file size", ErrInvalidSealedFile)
+	}
+	if int(footer.codec) >= len(sealCodecs) {
+		return nil, nil, fmt.Errorf("%w: unknown codec %d", ErrInvalidSealedFile, footer.codec)
+	}
+
+	index := make([]byte, indexSize)
+	if _, err := r.ReadAt(index, int64(footer.indexOffset)); err != nil {
+		return nil, nil, err
+	}
+	if crc32.Update(crc32.Checksum(index, crcTable), crcTable, buf[:37]) != sum {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidSealedFile, ErrChecksumMismatch)
+	}
+
+	blocks := make([]sealBlock, footer.blocks)
+	for i := range blocks {
+		entry := index[i*sealIndexEntrySize:]
+		blocks[i] = sealBlock{
+			min:    int64(binary.BigEndian.Uint64(entry[0:8])),
+			offset: binary.BigEndian.Uint64(entry[8:16]),
+			size:   binary.BigEndian.Uint32(entry[16:20]),
+			count:  binary.BigEndian.Uint32(entry[20:24]),
+			sum:    binary.BigEndian.Uint32(entry[24:28]),
+		}
+		if blocks[i].offset+uint64(blocks[i].size) > footer.indexOffset {
+			return nil, nil, fmt.Errorf("%w: block %d is out of bounds", ErrInvalidSealedFile, i)
+		}
+	}
+
+	return footer, blocks, nil
+}
+
+func sealedBlockData(footer *sealFooter, block sealBlock, raw []byte) ([]byte, error) {
+	if crc32.Checksum(raw, crcTable) != block.sum {
+		return nil, ErrChecksumMismatch
+	}
+
+	codec := codecs[sealCodecs[footer.codec]]
+	if codec.decompress == nil {
+		return raw, nil
+	}
+	return codec.decompress(raw)
+}
+
+func (c *Client[T]) sealedPath(bucket time.Time) string {
+	return strings.TrimSuffix(c.timeToPath(bucket), c.ext()) + sealedExt
+}
+
+func (c *Client[T]) sealCompression() string {
+	if c.compression() == CompressionNone {
+		return CompressionZstd
+	}
+	return c.compression()
+}
+
+func (c *Client[T]) readSealedBlocks(path string, from time.Time, to time.Time) (*sealFooter, []sealBlock, [][]byte, error) {
+	lock := c.fileLock(path)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	f, err := openLocked(path, os.O_RDONLY, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	footer, blocks, err := readSealedIndex(f, info.Size())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	fromNanos, toNanos := sealNanos(from), sealNanos(to)
+	if footer.count == 0 || footer.max < fromNanos || footer.min > toNanos {
+		return footer, nil, nil, nil
+	}
+
+	first := sort.Search(len(blocks), func(i int) bool {
+		return blocks[i].min >= fromNanos
+	})
+	first = max(first-1, 0)
+
+	var selected []sealBlock
+	var data [][]byte
+	for _, block := range blocks[first:] {
+		if block.min > toNanos {
+			break
+		}
+		raw := make([]byte, block.size)
+		if _, err := f.ReadAt(raw, int64(block.offset)); err != nil {
+			return nil, nil, nil, err
+		}
+		selected = append(selected, block)
+		data = append(data, raw)
+	}
+
+	return footer, selected, data, nil
+}
+
+func (c *Client[T]) readSealed(ctx context.Context, path string, from time.Time, to time.Time, fn func(t time.Time, data T) bool) (bool, error) {
+	footer, blocks, data, err := c.readSealedBlocks(path, from, to)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	skipCorrupt := c.Opts.Corruption == CorruptSkip
+	for i, raw := range data {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		plain, err := sealedBlockData(footer, blocks[i], raw)
+		if err != nil {
+			if !skipCorrupt {
+				return false, &CorruptRecordError{Path: path, Offset: int64(blocks[i].offset), Err: err}
+			}
+			c.corrupt.Add(int64(blocks[i].count))
+			continue
+		}
+
+		r := &recordReader[T]{buf: plain, framed: c.Opts.Checksums, skipCorrupt: skipCorrupt}
+		shouldContinue, past := true, false
+		var ctxErr error
+		_, err = decodeEntries(r, func(entry Entry[T]) bool {
+			if ctxErr = ctx.Err(); ctxErr != nil {
+				return false
+			}
+			if entry.Time.Before(from) {
+				return true
+			}
+			if entry.Time.After(to) {
+				past = true
+				return false
+			}
+			shouldContinue = fn(entry.Time, entry.Data)
+			return shouldContinue
+		})
+		c.corrupt.Add(int64(r.corrupt))
+		if corruptErr, ok := err.(*CorruptRecordError); ok {
+			corruptErr.Path = path
+			corruptErr.Offset = int64(blocks[i].offset)
+		}
+		if err != nil {
+			return false, err
+		}
+		if ctxErr != nil {
+			return false, ctxErr
+		}
+		if !shouldContinue || past {
+			return shouldContinue, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (c *Client[T]) scanSealed(path string, buf []byte, fn func(entry Entry[T], raw []byte)) (*sealFooter, error) {
+	footer, blocks, err := readSealedIndex(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	skipCorrupt := c.Opts.Corruption == CorruptSkip
+	for _, block := range blocks {
+		plain, err := sealedBlockData(footer, block, buf[block.offset:block.offset+uint64(block.size)])
+		if err != nil {
+			if !skipCorrupt {
+				return nil, &CorruptRecordError{Path: path, Offset: int64(block.offset), Err: err}
+			}
+			c.corrupt.Add(int64(block.count))
+			continue
+		}
+
+		r := &recordReader[T]{buf: plain, framed: c.Opts.Checksums, skipCorrupt: skipCorrupt}
+		for {
+			start := r.off
+			entry, err := r.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				if corruptErr, ok := err.(*CorruptRecordError); ok {
+					corruptErr.Path = path
+					corruptErr.Offset = int64(block.offset)
+				}
+				return nil, err
+			}
+			fn(entry, plain[start:r.off])
+		}
+		c.corrupt.Add(int64(r.corrupt))
+	}
+
+	return footer, nil
+}
+
+func (c *Client[T]) Seal(bucket time.Time) error {
+	if err := c.writable(); err != nil {
+		return err
+	}
+
+	return c.sealBucket(c.bucketStart(bucket), &CompactReport{})
+}
+
+func (c *Client[T]) Compact(ctx context.Context, before time.Time) (*CompactReport, error) {
+	if err := c.writable(); err != nil {
+		return nil, err
+	}
+
+	report := &CompactReport{Before: before}
+	if c.Opts.Path == "" {
+		return report, nil
+	}
+
+	if err := c.loadYears(); err != nil {
+		return report, err
+	}
+
+	var errs []error
+	for _, bucket := range c.cachedBuckets() {
+		if c.nextBucket(bucket).After(before) {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		if _, err := os.Stat(c.timeToPath(bucket)); os.IsNotExist(err) {
+			continue
+		}
+
+		if err := c.sealBucket(bucket, report); err != nil {
+			if report.Failed == nil {
+				report.Failed = make(map[time.Time]error)
+			}
+			report.Failed[bucket] = err
+			errs = append(errs, fmt.Errorf("sealing %s: %w", bucket, err))
+		}
+	}
+
+	return report, errors.Join(errs...)
+}
+
+func (c *Client[T]) sealBucket(bucket time.Time, report *CompactReport) error {
+	path := c.timeToPath(bucket)
+	sealed := c.sealedPath(bucket)
+
+	c.dirMu.RLock()
+	defer c.dirMu.RUnlock()
+
+	lock := c.fileLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	sealedLock := c.fileLock(sealed)
+	sealedLock.Lock()
+	defer sealedLock.Unlock()
+
+	f, err := openLocked(path, os.O_RDWR, true)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	var records []sealedRecord
+	existing, err := os.ReadFile(sealed)
+	if err == nil {
+		_, err = c.scanSealed(sealed, existing, func(entry Entry[T], raw []byte) {
+			records = append(records, sealedRecord{time: entry.Time, raw: raw})
+		})
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	skipCorrupt := c.Opts.Corruption == CorruptSkip
+	var onCorrupt func(offset int64, err error)
+	if skipCorrupt {
+		onCorrupt = func(offset int64, err error) {
+			c.corrupt.Add(1)
+		}
+	}
+	plain, _, err := c.decompress(buf, onCorrupt)
+	if errors.Is(err, io.ErrUnexpectedEOF) && c.Opts.RecoverTornWrites {
+		err = nil
+	}
+
+	r := &recordReader[T]{buf: plain, framed: c.Opts.Checksums, skipCorrupt: skipCorrupt}
+	for err == nil {
+		start := r.off
+		var entry Entry[T]
+		entry, err = r.next()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) && c.Opts.RecoverTornWrites {
+			err = nil
+			break
+		}
+		if err == nil {
+			records = append(records, sealedRecord{time: entry.Time, raw: plain[start:r.off]})
+		}
+	}
+	c.corrupt.Add(int64(r.corrupt))
+	if err != nil {
+		if corruptErr, ok := err.(*CorruptRecordError); ok {
+			corruptErr.Path = path
+		}
+		return err
+	}
+
+	slices.SortStableFunc(records, func(a, b sealedRecord) int {
+		return a.time.Compare(b.time)
+	})
+
+	duplicates := 0
+	deduped := records[:0]
+	for _, record := range records {
+		duplicate := false
+		for j := len(deduped) - 1; j >= 0 && deduped[j].time.Equal(record.time); j-- {
+			if bytes.Equal(deduped[j].raw, record.raw) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			duplicates++
+			continue
+		}
+		deduped = append(deduped, record)
+	}
+
+	if len(deduped) == 0 {
+		return nil
+	}
+
+	data, err := encodeSealed(deduped, c.sealCompression())
+	if err != nil {
+		return err
+	}
+
+	if err := c.replaceFile(sealed, data); err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	stats := bucketStats{
+		Size:  int64(len(data)),
+		Count: int64(len(deduped)),
+		Min:   deduped[0].time.Unix(),
+		Max:   deduped[len(deduped)-1].time.Unix(),
+	}
+	if stamp, err := c.bucketStamp(bucket); err == nil && stamp.Size == stats.Size {
+		stats.Files = stamp.Files
+	}
+	c.setStats(bucket, stats)
+
+	report.Sealed = append(report.Sealed, bucket)
+	report.Entries += len(deduped)
+	report.Duplicates += duplicates
+	report.Bytes += int64(len(data))
+	return nil
+}
+
+func (c *Client[T]) rewriteSealedLocked(path string, keep func(entry Entry[T]) bool) (int, int, int64, error) {
+	f, err := openLocked(path, os.O_RDWR, true)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, 0, nil
+		}
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var kept []sealedRecord
+	removed := 0
+	footer, err := c.scanSealed(path, buf, func(entry Entry[T], raw []byte) {
+		if keep(entry) {
+			kept = append(kept, sealedRecord{time: entry.Time, raw: raw})
+		} else {
+			removed++
+		}
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if removed == 0 {
+		return 0, len(kept), int64(len(buf)), nil
+	}
+
+	if len(kept) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return 0, 0, 0, err
+		}
+		return removed, 0, 0, nil
+	}
+
+	data, err := encodeSealed(kept, sealCodecs[footer.codec])
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := c.replaceFile(path, data); err != nil {
+		return 0, 0, 0, err
+	}
+
+	return removed, len(kept), int64(len(data)), nil
+}
+
+func (c *Client[T]) compactLoop() {
+	defer c.wg.Done()
+
+	interval := c.Opts.CompactInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		report, err := c.Compact(context.Background(), time.Now().Add(-c.Opts.SealAfter))
+		if errors.Is(err, ErrClosed) {
+			return
+		}
+		if c.Opts.OnCompact != nil {
+			c.Opts.OnCompact(report, err)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package timeseries
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_SealBucket(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for _, minute := range []int{30, 10, 50, 10, 20} {
+		if err := c.Store(base.Add(time.Duration(minute)*time.Minute), testStruct{SomeInt: minute}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Store(base.Add(10*time.Minute), testStruct{SomeInt: 11}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Seal(base); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(c.timeToPath(base)); !os.IsNotExist(err) {
+		t.Fatal("expected the append file to be removed after sealing")
+	}
+	if _, err := os.Stat(c.sealedPath(base)); err != nil {
+		t.Fatalf("expected a sealed file: %v", err)
+	}
+
+	reopened, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.getCache(base) {
+		t.Fatal("expected sealed file to be cached on init")
+	}
+
+	results, err := reopened.Get(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{10, 11, 20, 30, 50}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d deduplicated results, got %d", len(expected), len(results))
+	}
+	for i, result := range results {
+		if result.SomeInt != expected[i] {
+			t.Fatalf("expected sorted results %v, got %d at %d", expected, result.SomeInt, i)
+		}
+	}
+
+	report, err := reopened.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Entries != len(expected) || len(report.Issues) != 0 {
+		t.Fatalf("expected a clean report, got %+v", report)
+	}
+}
+
+func Test_SealLateWrites(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, base.Add(10*time.Second), 3)
+	if err := c.Seal(base); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Store(base.Add(5*time.Second), testStruct{SomeInt: 99}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(c.timeToPath(base)); err != nil {
+		t.Fatalf("expected late write to go to an append file: %v", err)
+	}
+
+	results, err := c.Get(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results from sealed and append files, got %d", len(results))
+	}
+
+	if err := c.Seal(base); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(c.timeToPath(base)); !os.IsNotExist(err) {
+		t.Fatal("expected the append file to be merged into the sealed file")
+	}
+
+	var ints []int
+	err = c.Find(base, base.Add(time.Hour), func(_ time.Time, data testStruct) bool {
+		ints = append(ints, data.SomeInt)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ints) != 4 || ints[0] != 99 {
+		t.Fatalf("expected the late write merged in time order, got %v", ints)
+	}
+}
+
+func Test_SealedRangeReads(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Bucket: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	entries := make([]Entry[testStruct], 3*sealBlockEntries)
+	for i := range entries {
+		entries[i] = Entry[testStruct]{Time: base.Add(time.Duration(i) * time.Second), Data: testStruct{SomeInt: i}}
+	}
+	if err := c.StoreBatch(entries); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Seal(base); err != nil {
+		t.Fatal(err)
+	}
+
+	from := base.Add(time.Duration(sealBlockEntries+10) * time.Second)
+	to := from.Add(20 * time.Second)
+
+	_, blocks, _, err := c.readSealedBlocks(c.sealedPath(base), from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 {
+		t.Fatalf("expected a narrow range to read a single block, read %d", len(blocks))
+	}
+
+	_, blocks, _, err = c.readSealedBlocks(c.sealedPath(base), base.Add(-time.Hour), base.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 0 {
+		t.Fatalf("expected a range outside the footer to read no blocks, read %d", len(blocks))
+	}
+
+	results, err := c.Get(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 21 || results[0].SomeInt != sealBlockEntries+10 {
+		t.Fatalf("expected 21 results starting at %d, got %d", sealBlockEntries+10, len(results))
+	}
+
+	results, err = c.Get(base, base.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(entries) {
+		t.Fatalf("expected %d results, got %d", len(entries), len(results))
+	}
+}
+
+func Test_SealedFarRange(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	late := time.Date(2300, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		if err := c.Store(base.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Store(late.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: 10 + i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ranges := [][2]time.Time{
+		{base, time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(1500, 1, 1, 0, 0, 0, 0, time.UTC), base.Add(time.Hour)},
+		{late, late.Add(time.Hour)},
+	}
+	for _, seal := range []bool{false, true} {
+		if seal {
+			for _, bucket := range []time.Time{base, late} {
+				if err := c.Seal(bucket); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		for _, r := range ranges {
+			results, err := c.Get(r[0], r[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 5 {
+				t.Fatalf("expected 5 results for %s - %s (sealed %v), got %d", r[0], r[1], seal, len(results))
+			}
+		}
+	}
+}
+
+func Test_SealedDelete(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, base, 6)
+	if err := c.Seal(base); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Store(base.Add(5*time.Minute+30*time.Second), testStruct{SomeInt: 50}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Delete(base, base.Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := c.DeleteWhere(base, base.Add(time.Hour), func(_ time.Time, data testStruct) bool {
+		return data.SomeInt == 50 || data.SomeInt == 4
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed[base] != 2 {
+		t.Fatalf("expected 2 entries removed from the sealed and append files, got %v", removed)
+	}
+
+	results, err := c.Get(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].SomeInt != 3 || results[1].SomeInt != 5 {
+		t.Fatalf("expected entries 3 and 5 to remain, got %d results", len(results))
+	}
+
+	if err := c.Delete(base, base.Add(time.Hour-time.Nanosecond)); err != nil {
+		t.Fatal(err)
+	}
+	if c.onDisk(base) || c.getCache(base) {
+		t.Fatal("expected the sealed bucket to be removed")
+	}
+}
+
+func Test_SealedCorruptBlock(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	storeAndRecordSizes(t, c, base, 5)
+	if err := c.Seal(base); err != nil {
+		t.Fatal(err)
+	}
+
+	flipByte(t, c.sealedPath(base), 3)
+
+	_, err = c.Get(base, base.Add(time.Hour))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	skipping, err := Init[testStruct](Options{Path: tmpDir, Corruption: CorruptSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := skipping.Get(base, base.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if skipping.CorruptRecords() != 5 {
+		t.Fatalf("expected the 5 entries of the block to be counted as corrupt, got %d", skipping.CorruptRecords())
+	}
+
+	report, err := c.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Kind != IssueCorruptRecord {
+		t.Fatalf("expected one corrupt block issue, got %+v", report.Issues)
+	}
+}
+
+func Test_Compact(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		storeAndRecordSizes(t, c, base.Add(time.Duration(i)*time.Hour), 2)
+	}
+
+	report, err := c.Compact(context.Background(), base.Add(2*time.Hour+30*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Sealed) != 2 || report.Entries != 4 {
+		t.Fatalf("expected 2 closed hours with 4 entries to be sealed, got %+v", report)
+	}
+
+	if _, err := os.Stat(c.timeToPath(base.Add(2 * time.Hour))); err != nil {
+		t.Fatal("expected the open hour to stay in the append format")
+	}
+
+	report, err = c.Compact(context.Background(), base.Add(2*time.Hour+30*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Sealed) != 0 {
+		t.Fatalf("expected nothing left to seal, got %+v", report)
+	}
+}
+
+func Test_CompactContinuesPastFailures(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		storeAndRecordSizes(t, c, base.Add(time.Duration(i)*time.Hour), 2)
+	}
+	if err := os.WriteFile(c.timeToPath(base), []byte{0x1c, 0x1c, 0x1c}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := c.Compact(context.Background(), base.Add(3*time.Hour))
+	var corruptErr *CorruptRecordError
+	if !errors.As(err, &corruptErr) {
+		t.Fatalf("expected the damaged hour to be reported, got %v", err)
+	}
+	if len(report.Failed) != 1 || report.Failed[base] == nil {
+		t.Fatalf("expected the damaged hour to be recorded as failed, got %+v", report.Failed)
+	}
+	if len(report.Sealed) != 2 || report.Entries != 4 {
+		t.Fatalf("expected the other hours to be sealed, got %+v", report)
+	}
+}
+
+func Test_CompactInBackground(t *testing.T) {
+	reports := make(chan *CompactReport, 10)
+
+	c, err := Init[testStruct](Options{
+		Path:            t.TempDir(),
+		SealAfter:       time.Minute,
+		CompactInterval: 10 * time.Millisecond,
+		OnCompact: func(report *CompactReport, err error) {
+			if err == nil && len(report.Sealed) > 0 {
+				reports <- report
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	old := time.Now().Add(-3 * time.Hour)
+	if err := c.Store(old, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Store(time.Now(), testStruct{SomeInt: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case report := <-reports:
+		if len(report.Sealed) != 1 || !report.Sealed[0].Equal(c.bucketStart(old)) {
+			t.Fatalf("expected only the old hour to be sealed, got %+v", report)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for background compaction")
+	}
+}
@@ -0,0 +1,303 @@
+package timeseries
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+)
+
+type fileID struct {
+	_   struct{} `cbor:",toarray"`
+	Mod int64
+	Ino uint64
+}
+
+type bucketStats struct {
+	Size  int64
+	Count int64
+	Min   int64
+	Max   int64
+	Files [2]fileID
+}
+
+var unknownStats = bucketStats{Size: -1, Count: -1}
+
+func (s *bucketStats) add(t time.Time) {
+	sec := t.Unix()
+	if s.Count == 0 || sec < s.Min {
+		s.Min = sec
+	}
+	if s.Count == 0 || sec > s.Max {
+		s.Max = sec
+	}
+	s.Count++
+}
+
+func (s bucketStats) merge(o bucketStats) bucketStats {
+	if s.Size < 0 || o.Size < 0 {
+		s.Size = -1
+	} else {
+		s.Size += o.Size
+	}
+
+	switch {
+	case s.Count < 0 || o.Count < 0:
+		s.Count = -1
+	case s.Count == 0:
+		s.Count, s.Min, s.Max = o.Count, o.Min, o.Max
+	case o.Count > 0:
+		s.Count += o.Count
+		s.Min = min(s.Min, o.Min)
+		s.Max = max(s.Max, o.Max)
+	}
+	return s
+}
+
+func (s *bucketStats) addFile(path string, info os.FileInfo) {
+	slot := 0
+	if strings.HasSuffix(path, sealedExt) {
+		slot = 1
+	}
+	s.Size += info.Size()
+	s.Files[slot] = fileID{Mod: info.ModTime().UnixNano(), Ino: fileInode(info)}
+}
+
+func (s bucketStats) current(stamp bucketStats) bool {
+	return s.Count >= 0 && s.Size == stamp.Size && s.Files == stamp.Files
+}
+
+func (s bucketStats) appended(stamp bucketStats, n int64) bool {
+	if s.Size+n != stamp.Size || s.Files[1] != stamp.Files[1] {
+		return false
+	}
+	return s.Size == 0 || (s.Files[0] != fileID{} && s.Files[0].Ino == stamp.Files[0].Ino)
+}
+
+func (s bucketStats) overlaps(from time.Time, to time.Time) bool {
+	return !time.Unix(s.Max, 0).Before(from) && !time.Unix(s.Min, 0).After(to)
+}
+
+func (s bucketStats) within(from time.Time, to time.Time) bool {
+	return !time.Unix(s.Min, 0).Before(from) && !time.Unix(s.Max, 0).After(to)
+}
+
+func (c *Client[T]) setStatsLocked(bucket time.Time, s bucketStats) {
+	y, i := c.cacheKey(bucket)
+	c.setPresentLocked(y, i)
+
+	if c.stats == nil {
+		c.stats = make(map[int64]bucketStats)
+	}
+	c.stats[bucket.Unix()] = s
+}
+
+func (c *Client[T]) addStats(bucket time.Time, added bucketStats) {
+	stamp, err := c.bucketStamp(bucket)
+
+	c.mu.Lock()
+	s, ok := c.stats[bucket.Unix()]
+	if !ok {
+		s = unknownStats
+		if !c.cachedLocked(bucket) {
+			s = bucketStats{}
+		}
+	}
+	merged := s.merge(added)
+	merged.Files = [2]fileID{}
+	if err == nil && s.appended(stamp, added.Size) {
+		merged.Files = stamp.Files
+	}
+	c.setStatsLocked(bucket, merged)
+	c.mu.Unlock()
+
+	c.indexChanged(bucket)
+}
+
+func (c *Client[T]) setStats(bucket time.Time, s bucketStats) {
+	c.mu.Lock()
+	c.setStatsLocked(bucket, s)
+	c.mu.Unlock()
+
+	c.indexChanged(bucket)
+}
+
+func (c *Client[T]) learnStats(bucket time.Time, s bucketStats) {
+	c.mu.Lock()
+	if c.cachedLocked(bucket) {
+		c.setStatsLocked(bucket, s)
+	}
+	c.mu.Unlock()
+
+	if c.indexed() && !c.Opts.ReadOnly {
+		c.indexMu.Lock()
+		c.indexDirty = true
+		c.indexMu.Unlock()
+	}
+}
+
+func (c *Client[T]) bucketStamp(bucket time.Time) (bucketStats, error) {
+	stamp := bucketStats{Count: -1}
+	for _, path := range []string{c.timeToPath(bucket), c.sealedPath(bucket)} {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return bucketStats{}, err
+		}
+		stamp.addFile(path, info)
+	}
+	return stamp, nil
+}
+
+func (c *Client[T]) cachedStats(bucket time.Time) (bucketStats, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.stats[bucket.Unix()]
+	return s, ok && s.Count >= 0
+}
+
+func (c *Client[T]) bucketStats(ctx context.Context, bucket time.Time) (bucketStats, error) {
+	stamp, err := c.bucketStamp(bucket)
+	if err != nil {
+		return bucketStats{}, err
+	}
+
+	if s, ok := c.cachedStats(bucket); ok && s.current(stamp) {
+		return s, nil
+	}
+
+	entries, err := c.loadBucket(ctx, bucket, time.Time{}, endOfTime, false)
+	if err != nil {
+		return bucketStats{}, err
+	}
+
+	s := stamp
+	s.Count = 0
+	for _, entry := range entries {
+		s.add(entry.Time)
+	}
+	return s, nil
+}
+
+func (c *Client[T]) skipBucket(bucket time.Time, from time.Time, to time.Time) bool {
+	s, ok := c.cachedStats(bucket)
+	if !ok || s.Count == 0 || s.overlaps(from, to) {
+		return false
+	}
+
+	stamp, err := c.bucketStamp(bucket)
+	return err == nil && s.current(stamp)
+}
+
+func (c *Client[T]) Count(from time.Time, to time.Time) (int64, error) {
+	return c.CountContext(context.Background(), from, to)
+}
+
+func (c *Client[T]) CountContext(ctx context.Context, from time.Time, to time.Time) (int64, error) {
+	if c.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	var total int64
+	err := c.eachBucket(ctx, from, to, false, func(bucket time.Time) (bool, error) {
+		s, err := c.bucketStats(ctx, bucket)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case s.Count == 0 || !s.overlaps(from, to):
+		case s.within(from, to):
+			total += s.Count
+		default:
+			entries, err := c.loadBucket(ctx, bucket, from, to, false)
+			if err != nil {
+				return false, err
+			}
+			total += int64(len(entries))
+		}
+		return true, nil
+	})
+	return total, err
+}
+
+func (c *Client[T]) Size(from time.Time, to time.Time) (int64, error) {
+	if c.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	var total int64
+	err := c.eachBucket(context.Background(), from, to, false, func(bucket time.Time) (bool, error) {
+		c.mu.RLock()
+		s, ok := c.stats[bucket.Unix()]
+		c.mu.RUnlock()
+
+		if !ok || s.Size < 0 {
+			size, err := c.bucketSize(bucket)
+			if err != nil {
+				return false, err
+			}
+			s.Size = size
+		}
+		total += s.Size
+		return true, nil
+	})
+	return total, err
+}
+
+func (c *Client[T]) Extent(from time.Time, to time.Time) (time.Time, time.Time, error) {
+	return c.ExtentContext(context.Background(), from, to)
+}
+
+func (c *Client[T]) ExtentContext(ctx context.Context, from time.Time, to time.Time) (time.Time, time.Time, error) {
+	if c.closed.Load() {
+		return time.Time{}, time.Time{}, ErrClosed
+	}
+
+	first, err := c.edge(ctx, from, to, false)
+	if err != nil || first.IsZero() {
+		return time.Time{}, time.Time{}, err
+	}
+
+	last, err := c.edge(ctx, from, to, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return first, last, nil
+}
+
+func (c *Client[T]) edge(ctx context.Context, from time.Time, to time.Time, reverse bool) (time.Time, error) {
+	var found time.Time
+	err := c.eachBucket(ctx, from, to, reverse, func(bucket time.Time) (bool, error) {
+		s, err := c.bucketStats(ctx, bucket)
+		if err != nil {
+			return false, err
+		}
+		if s.Count == 0 || !s.overlaps(from, to) {
+			return true, nil
+		}
+
+		if !reverse && !time.Unix(s.Min, 0).Before(from) {
+			found = time.Unix(s.Min, 0)
+			return false, nil
+		}
+		if reverse && !time.Unix(s.Max, 0).After(to) {
+			found = time.Unix(s.Max, 0)
+			return false, nil
+		}
+
+		entries, err := c.loadBucket(ctx, bucket, from, to, reverse)
+		if err != nil {
+			return false, err
+		}
+		if len(entries) == 0 {
+			return true, nil
+		}
+		found = entries[0].Time
+		return false, nil
+	})
+	return found, err
+}
@@ -0,0 +1,282 @@
+package timeseries
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_CountSizeExtent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	var entries []Entry[testStruct]
+	for i := range 12 {
+		entries = append(entries, Entry[testStruct]{Time: base.Add(time.Duration(i) * 15 * time.Minute), Data: testStruct{SomeInt: i}})
+	}
+	if err := c.StoreBatch(entries); err != nil {
+		t.Fatal(err)
+	}
+	last := entries[len(entries)-1].Time
+
+	var files int64
+	for hour := range 3 {
+		fi, err := os.Stat(c.timeToPath(base.Add(time.Duration(hour) * time.Hour)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files += fi.Size()
+	}
+
+	reopened, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := reopened.cachedStats(base); ok {
+		t.Fatalf("expected a walk to leave counts unknown, got %+v", s)
+	}
+
+	for _, client := range []*Client[testStruct]{c, reopened} {
+		count, err := client.Count(base, last)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 12 {
+			t.Fatalf("expected 12 entries, got %d", count)
+		}
+
+		count, err = client.Count(base.Add(20*time.Minute), base.Add(100*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 5 {
+			t.Fatalf("expected 5 entries in the partial range, got %d", count)
+		}
+
+		size, err := client.Size(base, last)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != files {
+			t.Fatalf("expected %d bytes, got %d", files, size)
+		}
+
+		first, end, err := client.Extent(base.Add(-time.Hour), last.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !first.Equal(base) || !end.Equal(last) {
+			t.Fatalf("expected extent %s - %s, got %s - %s", base, last, first, end)
+		}
+
+		first, end, err = client.Extent(base.Add(20*time.Minute), base.Add(100*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !first.Equal(base.Add(30*time.Minute)) || !end.Equal(base.Add(90*time.Minute)) {
+			t.Fatalf("unexpected partial extent %s - %s", first, end)
+		}
+
+		first, end, err = client.Extent(base.AddDate(0, 0, 1), base.AddDate(0, 0, 2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !first.IsZero() || !end.IsZero() {
+			t.Fatalf("expected an empty extent, got %s - %s", first, end)
+		}
+
+		if s, ok := client.cachedStats(base); !ok || s.Count != 4 {
+			t.Fatalf("expected known stats after querying, got %+v", s)
+		}
+	}
+
+	if err := c.Seal(base); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := sealed.Count(base, base.Add(59*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 entries in the sealed hour, got %d", count)
+	}
+	first, end, err := sealed.Extent(base, base.Add(59*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Equal(base) || !end.Equal(base.Add(45*time.Minute)) {
+		t.Fatalf("unexpected extent of the sealed hour %s - %s", first, end)
+	}
+}
+
+func Test_FindSkipsBucketsByMinMax(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		if err := c.Store(base.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := c.timeToPath(base)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bytes.Repeat([]byte{0xff}, int(fi.Size())), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Get(base.Add(30*time.Minute), base.Add(59*time.Minute))
+	if err != nil {
+		t.Fatalf("expected the bucket to be skipped without reading it: %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected no results, got %d", len(results))
+	}
+
+	if _, err := c.Get(base, base.Add(5*time.Minute)); err == nil {
+		t.Fatal("expected an overlapping range to read the damaged bucket")
+	}
+
+	other, err := Init[testStruct](Options{Path: c.Opts.Path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Store(base.Add(45*time.Minute), testStruct{SomeInt: 45}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = c.Get(base.Add(30*time.Minute), base.Add(59*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SomeInt != 45 {
+		t.Fatalf("expected a bucket changed by another client to be read, got %v", results)
+	}
+}
+
+func Test_StatsFollowFileIdentity(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	a, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := b.Store(base.Add(50*time.Minute), testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	path := b.timeToPath(base)
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Store(base.Add(5*time.Minute), testStruct{SomeInt: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Delete(base.Add(45*time.Minute), base.Add(55*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() != before.Size() {
+		t.Fatalf("expected the rewritten file to keep its size, got %d and %d", before.Size(), after.Size())
+	}
+
+	results, err := b.Get(base, base.Add(10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SomeInt != 2 {
+		t.Fatalf("expected the entry written by the other client, got %v", results)
+	}
+
+	count, err := b.Count(base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 entry, got %d", count)
+	}
+
+	if err := b.Refresh(base, base.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := b.cachedStats(base); !ok || s.Min != base.Add(5*time.Minute).Unix() {
+		t.Fatalf("expected stats for the rewritten file, got %+v", s)
+	}
+}
+
+func Test_FindLearnsStats(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		if err := c.Store(base.Add(time.Duration(i)*10*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.cachedStats(base); ok {
+		t.Fatal("expected a walk to leave counts unknown")
+	}
+
+	if _, err := reopened.Get(base.Add(5*time.Minute), base.Add(15*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.cachedStats(base); ok {
+		t.Fatal("expected a partial read to leave counts unknown")
+	}
+
+	results, err := reopened.Get(base.Add(-time.Minute), base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	s, ok := reopened.cachedStats(base)
+	if !ok || s.Count != 3 || s.Min != base.Unix() || s.Max != base.Add(20*time.Minute).Unix() {
+		t.Fatalf("expected a whole-bucket read to record stats, got %+v", s)
+	}
+	if !reopened.skipBucket(base, base.Add(30*time.Minute), base.Add(40*time.Minute)) {
+		t.Fatal("expected the learned stats to let Find skip the bucket")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package timeseries
+
+import "os"
+
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+func fileInode(fi os.FileInfo) uint64 {
+	return 0
+}
+
+func syncDir(dir string, fsync func(f *os.File) error) error {
+	return nil
+}
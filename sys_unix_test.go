@@ -0,0 +1,59 @@
+//go:build unix
+
+package timeseries
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_StoreWaitsForFileLock(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	path := c.timeToPath(baseTime)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lockFile(f, true); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Store(baseTime, testStruct{SomeInt: 1})
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("store completed while another process held the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	f.Close()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Get(baseTime, baseTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+}
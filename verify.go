@@ -0,0 +1,275 @@
+package timeseries
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type VerifyIssueKind string
+
+const (
+	IssueInvalidPath        VerifyIssueKind = "invalid_path"
+	IssueEmptyFile          VerifyIssueKind = "empty_file"
+	IssueUndecodableRecord  VerifyIssueKind = "undecodable_record"
+	IssueCorruptRecord      VerifyIssueKind = "corrupt_record"
+	IssueTornWrite          VerifyIssueKind = "torn_write"
+	IssueEntryOutsideBucket VerifyIssueKind = "entry_outside_bucket"
+	IssueInvalidSealedFile  VerifyIssueKind = "invalid_sealed_file"
+	IssueReadError          VerifyIssueKind = "read_error"
+)
+
+type VerifyIssue struct {
+	Path    string          `json:"path"`
+	Kind    VerifyIssueKind `json:"kind"`
+	Offset  int64           `json:"offset"`
+	Time    *time.Time      `json:"time,omitempty"`
+	Message string          `json:"message"`
+}
+
+type VerifyReport struct {
+	Path    string        `json:"path"`
+	Files   int           `json:"files"`
+	Entries int           `json:"entries"`
+	Issues  []VerifyIssue `json:"issues"`
+}
+
+func (c *Client[T]) Verify(ctx context.Context) (*VerifyReport, error) {
+	if c.closed.Load() {
+		return nil, ErrClosed
+	}
+
+	report := &VerifyReport{
+		Path:   c.Opts.Path,
+		Issues: []VerifyIssue{},
+	}
+
+	if _, err := os.Stat(c.Opts.Path); os.IsNotExist(err) {
+		return report, nil
+	}
+
+	err := filepath.Walk(c.Opts.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if info.IsDir() || !c.isDataFile(path) {
+			return nil
+		}
+
+		report.Files++
+		c.verifyFile(path, info, report)
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (c *Client[T]) verifyFile(path string, info os.FileInfo, report *VerifyReport) {
+	bucket, err := c.parsePathToTime(path)
+	if err != nil {
+		report.Issues = append(report.Issues, VerifyIssue{
+			Path:    path,
+			Kind:    IssueInvalidPath,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if info.Size() == 0 {
+		report.Issues = append(report.Issues, VerifyIssue{
+			Path:    path,
+			Kind:    IssueEmptyFile,
+			Message: "file contains no records",
+		})
+		return
+	}
+
+	buf, err := c.readLocked(path)
+	if err != nil {
+		report.Issues = append(report.Issues, VerifyIssue{
+			Path:    path,
+			Kind:    IssueReadError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if strings.HasSuffix(path, sealedExt) {
+		c.verifySealed(path, bucket, buf, report)
+		return
+	}
+
+	onCorrupt := func(offset int64, err error) {
+		report.Issues = append(report.Issues, VerifyIssue{
+			Path:    path,
+			Kind:    IssueCorruptRecord,
+			Offset:  offset,
+			Message: err.Error(),
+		})
+	}
+
+	plain, blocksValid, blockErr := c.decompress(buf, onCorrupt)
+	if blockErr != nil {
+		defer func() {
+			report.Issues = append(report.Issues, VerifyIssue{
+				Path:    path,
+				Kind:    IssueTornWrite,
+				Offset:  blocksValid,
+				Message: fmt.Sprintf("%d trailing bytes do not form a complete block", len(buf)-int(blocksValid)),
+			})
+		}()
+	}
+
+	r := &recordReader[T]{
+		buf:         plain,
+		framed:      c.Opts.Checksums,
+		skipCorrupt: true,
+		onCorrupt:   onCorrupt,
+	}
+
+	for {
+		offset := int64(r.off)
+		entry, err := r.next()
+		if err == io.EOF {
+			return
+		}
+
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			report.Issues = append(report.Issues, VerifyIssue{
+				Path:    path,
+				Kind:    IssueTornWrite,
+				Offset:  offset,
+				Message: fmt.Sprintf("%d trailing bytes do not form a complete record", len(plain)-int(offset)),
+			})
+			return
+		}
+
+		if err != nil {
+			var corruptErr *CorruptRecordError
+			if errors.As(err, &corruptErr) {
+				err = corruptErr.Err
+			}
+			report.Issues = append(report.Issues, VerifyIssue{
+				Path:    path,
+				Kind:    IssueUndecodableRecord,
+				Offset:  offset,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		report.Entries++
+		c.verifyEntryBucket(path, offset, bucket, entry, report)
+	}
+}
+
+func (c *Client[T]) verifyEntryBucket(path string, offset int64, bucket time.Time, entry Entry[T], report *VerifyReport) {
+	if c.bucketStart(entry.Time).Equal(bucket) {
+		return
+	}
+
+	entryTime := entry.Time
+	report.Issues = append(report.Issues, VerifyIssue{
+		Path:    path,
+		Kind:    IssueEntryOutsideBucket,
+		Offset:  offset,
+		Time:    &entryTime,
+		Message: fmt.Sprintf("entry time %s is outside of bucket %s", entry.Time.Format(time.RFC3339Nano), bucket.Format(time.RFC3339)),
+	})
+}
+
+func (c *Client[T]) verifySealed(path string, bucket time.Time, buf []byte, report *VerifyReport) {
+	footer, blocks, err := readSealedIndex(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		report.Issues = append(report.Issues, VerifyIssue{
+			Path:    path,
+			Kind:    IssueInvalidSealedFile,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	count := uint64(0)
+	prev := time.Time{}
+	complete := true
+	for _, block := range blocks {
+		offset := int64(block.offset)
+		plain, err := sealedBlockData(footer, block, buf[block.offset:block.offset+uint64(block.size)])
+		if err != nil {
+			complete = false
+			report.Issues = append(report.Issues, VerifyIssue{
+				Path:    path,
+				Kind:    IssueCorruptRecord,
+				Offset:  offset,
+				Message: err.Error(),
+			})
+			continue
+		}
+
+		r := &recordReader[T]{
+			buf:         plain,
+			framed:      c.Opts.Checksums,
+			skipCorrupt: true,
+			onCorrupt: func(_ int64, err error) {
+				complete = false
+				report.Issues = append(report.Issues, VerifyIssue{
+					Path:    path,
+					Kind:    IssueCorruptRecord,
+					Offset:  offset,
+					Message: err.Error(),
+				})
+			},
+		}
+		for {
+			entry, err := r.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				complete = false
+				report.Issues = append(report.Issues, VerifyIssue{
+					Path:    path,
+					Kind:    IssueUndecodableRecord,
+					Offset:  offset,
+					Message: err.Error(),
+				})
+				break
+			}
+
+			count++
+			report.Entries++
+			if entry.Time.Before(prev) {
+				report.Issues = append(report.Issues, VerifyIssue{
+					Path:    path,
+					Kind:    IssueInvalidSealedFile,
+					Offset:  offset,
+					Message: fmt.Sprintf("entry time %s is before the previous entry", entry.Time.Format(time.RFC3339Nano)),
+				})
+			}
+			prev = entry.Time
+			c.verifyEntryBucket(path, offset, bucket, entry, report)
+		}
+	}
+
+	if complete && count != footer.count {
+		report.Issues = append(report.Issues, VerifyIssue{
+			Path:    path,
+			Kind:    IssueInvalidSealedFile,
+			Message: fmt.Sprintf("footer records %d entries, blocks hold %d", footer.count, count),
+		})
+	}
+}
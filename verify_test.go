@@ -0,0 +1,147 @@
+package timeseries
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func issuesByKind(report *VerifyReport) map[VerifyIssueKind]int {
+	kinds := make(map[VerifyIssueKind]int)
+	for _, issue := range report.Issues {
+		kinds[issue.Kind]++
+	}
+	return kinds
+}
+
+func Test_VerifyClean(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := c.Store(baseTime.Add(time.Duration(i)*time.Hour), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := c.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Files != 3 || report.Entries != 3 {
+		t.Fatalf("expected 3 files and 3 entries, got %d files and %d entries", report.Files, report.Entries)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("expected no issues, got %+v", report.Issues)
+	}
+}
+
+func Test_VerifyReportsIssues(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	c, err := Init[testStruct](Options{Path: tmpDir, Checksums: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+
+	sizes := storeAndRecordSizes(t, c, baseTime, 3)
+	flipByte(t, c.timeToPath(baseTime), sizes[0]+frameHeaderSize+2)
+
+	torn := baseTime.Add(time.Hour)
+	storeAndRecordSizes(t, c, torn, 2)
+	tearLastRecord(t, c.timeToPath(torn))
+
+	misplaced := baseTime.Add(2 * time.Hour)
+	var framed bytes.Buffer
+	if err := newRecordEncoder[testStruct](&framed, true).Encode(Entry[testStruct]{Time: baseTime, Data: testStruct{}}); err != nil {
+		t.Fatal(err)
+	}
+	encoded := framed.Bytes()
+	if err := os.WriteFile(c.timeToPath(misplaced), encoded, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(c.timeToPath(baseTime.Add(3*time.Hour)), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "2024", "06", "15", "bad.cbor"), encoded, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	undecodable := []byte{frameMarker, 0, 0, 0, 1, 0, 0, 0, 0, 0x1c}
+	binary.BigEndian.PutUint32(undecodable[5:9], lengthSum(undecodable[1:5], undecodable[9:]))
+	if err := os.WriteFile(c.timeToPath(baseTime.Add(4*time.Hour)), undecodable, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := c.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := issuesByKind(report)
+	expected := map[VerifyIssueKind]int{
+		IssueCorruptRecord:      2,
+		IssueTornWrite:          1,
+		IssueEntryOutsideBucket: 1,
+		IssueEmptyFile:          1,
+		IssueInvalidPath:        1,
+	}
+	for kind, count := range expected {
+		if kinds[kind] != count {
+			t.Fatalf("expected %d %s issues, got %d: %+v", count, kind, kinds[kind], report.Issues)
+		}
+	}
+
+	if report.Files != 6 {
+		t.Fatalf("expected 6 files, got %d", report.Files)
+	}
+
+	plain, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Store(baseTime, testStruct{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(plain.timeToPath(baseTime), []byte{0x1c}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report, err = plain.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kinds := issuesByKind(report); kinds[IssueUndecodableRecord] != 1 {
+		t.Fatalf("expected 1 %s issue in a plain store, got %+v", IssueUndecodableRecord, report.Issues)
+	}
+}
+
+func Test_VerifyContextCancelled(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Store(time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), testStruct{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.Verify(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package timeseries
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull    = errors.New("writer queue is full")
+	ErrWriterClosed = errors.New("writer is closed")
+)
+
+type OverflowPolicy int
+
+const (
+	OverflowError OverflowPolicy = iota
+	OverflowDrop
+)
+
+type WriterOptions struct {
+	FlushInterval time.Duration
+	FlushCount    int
+	FlushBytes    int
+	QueueSize     int
+	Overflow      OverflowPolicy
+	OnError       func(err error)
+}
+
+type Writer[T any] struct {
+	client *Client[T]
+	opts   WriterOptions
+
+	mu      sync.Mutex
+	queue   []Entry[T]
+	encoded [][]byte
+	bytes   int
+	dropped uint64
+	closed  bool
+
+	flushMu sync.Mutex
+	kick    chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (c *Client[T]) NewWriter(opts WriterOptions) *Writer[T] {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.FlushCount <= 0 {
+		opts.FlushCount = 1000
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = opts.FlushCount * 10
+	}
+
+	w := &Writer[T]{
+		client: c,
+		opts:   opts,
+		kick:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	c.writersMu.Lock()
+	if c.writable() != nil {
+		c.writersMu.Unlock()
+		w.closed = true
+		return w
+	}
+	if c.writers == nil {
+		c.writers = make(map[*Writer[T]]struct{})
+	}
+	c.writers[w] = struct{}{}
+	c.writersMu.Unlock()
+
+	w.wg.Add(1)
+	go w.loop()
+
+	return w
+}
+
+func (w *Writer[T]) Store(date time.Time, data T) error {
+	entry := Entry[T]{
+		Time: date,
+		Data: data,
+	}
+
+	var encoded bytes.Buffer
+	if err := newRecordEncoder[T](&encoded, w.client.Opts.Checksums).Encode(entry); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+
+	if len(w.queue) >= w.opts.QueueSize {
+		if w.opts.Overflow == OverflowDrop {
+			w.dropped++
+			w.mu.Unlock()
+			return nil
+		}
+		w.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	w.queue = append(w.queue, entry)
+	w.encoded = append(w.encoded, encoded.Bytes())
+	w.bytes += encoded.Len()
+	full := len(w.queue) >= w.opts.FlushCount ||
+		(w.opts.FlushBytes > 0 && w.bytes >= w.opts.FlushBytes)
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer[T]) Flush() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	queue, encoded := w.queue, w.encoded
+	w.queue, w.encoded = nil, nil
+	w.bytes = 0
+	w.mu.Unlock()
+
+	if len(queue) == 0 {
+		return nil
+	}
+
+	err := w.client.storeBatch(context.Background(), queue, encoded)
+	if err == nil {
+		return nil
+	}
+
+	var retry []int
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		for i, entryErr := range batchErr.Errs {
+			if entryErr != nil && retryable(entryErr) {
+				retry = append(retry, i)
+			}
+		}
+	} else if retryable(err) {
+		for i := range queue {
+			retry = append(retry, i)
+		}
+	}
+
+	w.requeue(queue, encoded, retry, len(queue)-len(retry))
+	return err
+}
+
+func (w *Writer[T]) requeue(queue []Entry[T], encoded [][]byte, retry []int, dropped int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if room := max(w.opts.QueueSize-len(w.queue), 0); len(retry) > room {
+		dropped += len(retry) - room
+		retry = retry[len(retry)-room:]
+	}
+	w.dropped += uint64(dropped)
+
+	entries := make([]Entry[T], 0, len(retry)+len(w.queue))
+	records := make([][]byte, 0, len(retry)+len(w.queue))
+	for _, i := range retry {
+		entries = append(entries, queue[i])
+		records = append(records, encoded[i])
+		w.bytes += len(encoded[i])
+	}
+	w.queue = append(entries, w.queue...)
+	w.encoded = append(records, w.encoded...)
+}
+
+func retryable(err error) bool {
+	var pathErr *fs.PathError
+	return errors.As(err, &pathErr)
+}
+
+func (w *Writer[T]) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.done)
+	w.wg.Wait()
+
+	w.client.writersMu.Lock()
+	delete(w.client.writers, w)
+	w.client.writersMu.Unlock()
+
+	return w.Flush()
+}
+
+func (w *Writer[T]) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.queue)
+}
+
+func (w *Writer[T]) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+func (w *Writer[T]) loop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		case <-w.kick:
+		}
+
+		if err := w.Flush(); err != nil && w.opts.OnError != nil {
+			w.opts.OnError(err)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package timeseries
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+func Test_WriterFlushOnClose(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{FlushInterval: time.Hour})
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		if err := w.Store(baseTime.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(100*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected nothing on disk before close, got %d", len(results))
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = c.Get(baseTime, baseTime.Add(100*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 100 {
+		t.Fatalf("expected 100 results after close, got %d", len(results))
+	}
+
+	if err := w.Store(baseTime, testStruct{}); !errors.Is(err, ErrWriterClosed) {
+		t.Fatalf("expected ErrWriterClosed, got %v", err)
+	}
+}
+
+func Test_WriterFlush(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{FlushInterval: time.Hour})
+	defer w.Close()
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := w.Store(baseTime, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Len() != 0 {
+		t.Fatalf("expected empty queue after flush, got %d", w.Len())
+	}
+
+	results, err := c.Get(baseTime, baseTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result after flush, got %d", len(results))
+	}
+}
+
+func Test_WriterAppendsQueuedRecords(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir(), Checksums: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{FlushInterval: time.Hour})
+	defer w.Close()
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := w.Store(baseTime.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var queued []byte
+	for _, record := range w.encoded {
+		queued = append(queued, record...)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := os.ReadFile(c.timeToPath(baseTime))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != string(queued) {
+		t.Fatal("expected the flushed file to hold exactly the queued records")
+	}
+
+	results, err := c.Get(baseTime, baseTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[2].SomeInt != 2 {
+		t.Fatalf("expected 3 results after flush, got %v", results)
+	}
+}
+
+func Test_WriterFlushOnCount(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{FlushInterval: time.Hour, FlushCount: 10})
+	defer w.Close()
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		if err := w.Store(baseTime.Add(time.Duration(i)*time.Second), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitForResults(t, c, baseTime, baseTime.Add(time.Minute), 10)
+}
+
+func Test_WriterFlushOnBytes(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{FlushInterval: time.Hour, FlushBytes: 1})
+	defer w.Close()
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := w.Store(baseTime, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForResults(t, c, baseTime, baseTime, 1)
+}
+
+func Test_WriterFlushOnInterval(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{FlushInterval: 10 * time.Millisecond})
+	defer w.Close()
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	if err := w.Store(baseTime, testStruct{SomeInt: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForResults(t, c, baseTime, baseTime, 1)
+}
+
+func Test_WriterQueueFull(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{FlushInterval: time.Hour, FlushCount: 100, QueueSize: 2})
+	defer w.Close()
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := w.Store(baseTime, testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Store(baseTime, testStruct{}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+func Test_WriterQueueFullDrop(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{
+		FlushInterval: time.Hour,
+		FlushCount:    100,
+		QueueSize:     2,
+		Overflow:      OverflowDrop,
+	})
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		if err := w.Store(baseTime, testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if w.Dropped() != 3 {
+		t.Fatalf("expected 3 dropped entries, got %d", w.Dropped())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Get(baseTime, baseTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+}
+
+func Test_WriterOnClosedClient(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := c.NewWriter(WriterOptions{})
+	if err := w.Store(time.Now(), testStruct{}); !errors.Is(err, ErrWriterClosed) {
+		t.Fatalf("expected ErrWriterClosed, got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.writers) != 0 {
+		t.Fatal("expected a writer of a closed client not to be registered")
+	}
+}
+
+func Test_WriterRetriesOnlyIOErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+
+	c, err := Init[testStruct](Options{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := c.NewWriter(WriterOptions{FlushInterval: time.Hour, FlushCount: 100})
+	defer w.Close()
+
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := w.Store(baseTime.Add(time.Duration(i)*time.Minute), testStruct{SomeInt: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Flush(); err == nil {
+		t.Fatal("expected the flush to fail while the store path is a file")
+	}
+	if w.Len() != 3 || w.Dropped() != 0 {
+		t.Fatalf("expected all entries to stay queued, got %d queued and %d dropped", w.Len(), w.Dropped())
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	other, err := Init[testStruct](Options{Path: path, Bucket: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Store(baseTime, testStruct{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Flush(); !errors.Is(err, ErrManifestMismatch) {
+		t.Fatalf("expected ErrManifestMismatch, got %v", err)
+	}
+	if w.Len() != 0 || w.Dropped() != 3 {
+		t.Fatalf("expected entries failing permanently to be dropped, got %d queued and %d dropped", w.Len(), w.Dropped())
+	}
+}
+
+func Test_WriterRequeueRespectsQueueSize(t *testing.T) {
+	c, err := Init[testStruct](Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := c.NewWriter(WriterOptions{FlushInterval: time.Hour, FlushCount: 100, QueueSize: 3})
+	defer w.Close()
+
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := w.Store(baseTime, testStruct{SomeInt: 10 + i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	failed := []Entry[testStruct]{
+		{Time: baseTime, Data: testStruct{SomeInt: 0}},
+		{Time: baseTime, Data: testStruct{SomeInt: 1}},
+		{Time: baseTime, Data: testStruct{SomeInt: 2}},
+	}
+	w.requeue(failed, [][]byte{{0}, {1}, {2}}, []int{0, 1, 2}, 0)
+
+	if w.Len() != 3 || w.Dropped() != 2 {
+		t.Fatalf("expected the queue to stay at its size, got %d queued and %d dropped", w.Len(), w.Dropped())
+	}
+	if w.queue[0].Data.SomeInt != 2 {
+		t.Fatalf("expected the newest failed entry to be kept, got %+v", w.queue[0])
+	}
+}
+
+func waitForResults(t *testing.T, c *Client[testStruct], from time.Time, to time.Time, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		results, err := c.Get(from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d results, got %d", want, len(results))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}